@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Accessible mode is for players using screen readers, and can be turned on per session from the title screen.
+// Mouse reporting and blinking are turned off, combat log lines lose their colors,
+// and everything that's usually shown by color or cursor position is also announced in the log as text:
+// whose turn it is, what's under the cursor, and how hurt or buffed units are.
+
+// DisableMouseReporting undoes EnableMouseReporting.
+const DisableMouseReporting = "\033[?1003l"
+
+// SetAccessible turns accessible mode on or off for this session.
+func (sesh *Sesh) SetAccessible(on bool) {
+	sesh.accessible = on
+	if on {
+		io.WriteString(sesh.ssh, DisableMouseReporting)
+	} else {
+		io.WriteString(sesh.ssh, EnableMouseReporting)
+	}
+	sesh.lastTurn = -1
+	sesh.looked = InvalidCoords
+}
+
+// announce adds a line of text to the combat log in accessible mode.
+func (sesh *Sesh) announce(text string) {
+	if !sesh.accessible || sesh.win == nil {
+		return
+	}
+	if n := len(sesh.win.Msgs); n > 0 && PlainText(sesh.win.Msgs[n-1]) == text {
+		return
+	}
+	sesh.win.Msgs = append(sesh.win.Msgs, GlyphsOf(text))
+}
+
+// announceChanges announces new turns and whatever the cursor moved onto since the last refresh.
+func (sesh *Sesh) announceChanges() {
+	if !sesh.accessible || sesh.win == nil {
+		return
+	}
+	w := sesh.world
+	gw := sesh.win
+	if w.turn != sesh.lastTurn && w.Up() != nil {
+		sesh.lastTurn = w.turn
+		sesh.announce(fmt.Sprintf("Turn %d: %s", w.turn, gw.describeTurn()))
+	}
+
+	top := sesh.TopWindow()
+	if _, ok := top.(interface{ cursorInput(string) bool }); !ok {
+		sesh.looked = InvalidCoords
+		return
+	}
+	cursor := top.Cursor()
+	if cursor == sesh.looked || !cursor.IsValid() || cursor.x >= gw.Map.Width() || cursor.y >= gw.Map.Height() {
+		return
+	}
+	sesh.looked = cursor
+	at := Loc{Map: gw.Map.Name, X: cursor.x, Y: cursor.y}
+	where := fmt.Sprintf("%d, %d", at.X, at.Y)
+	if m, ok := w.Up().(*Mob); ok && w.Sees(gw.Team, m) {
+		where += ", " + relativeTo(m.Loc(), at)
+	}
+	sesh.announce(where + ": " + describeAt(w, gw.Team, at))
+}
+
+// describeTurn says whose turn it is.
+func (gw *GameWindow) describeTurn() string {
+	switch up := gw.World.Up().(type) {
+	case *Mob:
+		if !gw.World.Sees(gw.Team, up) {
+			return "someone you can't see"
+		}
+		desc := up.Name() + "'s turn"
+		if up.Team() == gw.Team && !up.AIControlled(gw.World) {
+			desc += " (yours)"
+		}
+		loc := up.Loc()
+		return fmt.Sprintf("%s, at %d, %d. %s", desc, loc.X, loc.Y, describeMob(up))
+	case *Charge:
+		if !gw.World.Sees(gw.Team, up) {
+			return "a spell you can't see goes off"
+		}
+		return up.Name() + " goes off"
+	}
+	return "?"
+}
+
+// describeMob describes a unit's health, buffs, and anything else usually only shown by color.
+func describeMob(m *Mob) string {
+	if m.Dead() {
+		return "Dead."
+	}
+	desc := fmt.Sprintf("HP %d of %d", m.HP(), m.MaxHP())
+	if m.HP() <= m.MaxHP()/4 {
+		desc += ", badly hurt"
+	}
+	if m.MaxMP() > 0 {
+		desc += fmt.Sprintf(", MP %d of %d", m.MP(), m.MaxMP())
+	}
+	desc += ", facing " + m.Facing().String()
+	var buffs []string
+	for buff := range m.buffs {
+		buffs = append(buffs, buff.Label())
+	}
+	sort.Strings(buffs)
+	if len(buffs) > 0 {
+		desc += "; " + strings.Join(buffs, ", ")
+	}
+	if m.charging != nil {
+		desc += "; casting " + m.charging.spell.Name
+	}
+	return desc + "."
+}
+
+// describeAt describes the tile at the given location in plain text, like FarlookWindow.
+func describeAt(w *World, team int, at Loc) string {
+	tile := w.Map(at.Map).TileAt(at.X, at.Y)
+	if target, ok := tile.Top().(*Mob); ok && w.CanSee(team, at) {
+		side := "enemy"
+		if target.Team() == team {
+			side = "ally"
+		}
+		desc := fmt.Sprintf("%s (%s", target.Name(), side)
+		if target.class != "" {
+			desc += ", " + string(target.Class())
+		}
+		desc += ") on " + tile.Describe() + ". " + describeMob(target)
+		if aff := target.Affinities().Glyphs(); len(aff) > 0 {
+			desc += " " + PlainText(aff)
+		}
+		return desc
+	}
+	status, _ := lookAt(w, team, at, true)
+	desc := PlainText(status)
+	// drop the glyph, it's meaningless read aloud
+	if i := strings.Index(desc, "] "); strings.HasPrefix(desc, "[") && i >= 0 {
+		desc = desc[i+2:]
+	}
+	return desc
+}
+
+// relativeTo describes where to is from from, like "3 east, 2 north".
+func relativeTo(from, to Loc) string {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	var parts []string
+	switch {
+	case dx > 0:
+		parts = append(parts, fmt.Sprintf("%d east", dx))
+	case dx < 0:
+		parts = append(parts, fmt.Sprintf("%d west", -dx))
+	}
+	switch {
+	case dy > 0:
+		parts = append(parts, fmt.Sprintf("%d south", dy))
+	case dy < 0:
+		parts = append(parts, fmt.Sprintf("%d north", -dy))
+	}
+	if len(parts) == 0 {
+		return "here"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// nearbyUnits lists the units team can see, closest to from first, in plain text.
+func nearbyUnits(w *World, team int, from Loc) []string {
+	var units []*Mob
+	for _, obj := range w.Map(from.Map).Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() && w.Sees(team, mob) {
+			units = append(units, mob)
+		}
+	}
+	dist := func(m *Mob) int {
+		loc := m.Loc()
+		return abs(loc.X-from.X) + abs(loc.Y-from.Y)
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		return dist(units[i]) < dist(units[j])
+	})
+	lines := make([]string, 0, len(units))
+	for _, mob := range units {
+		side := "enemy"
+		if mob.Team() == team {
+			side = "ally"
+		}
+		loc := mob.Loc()
+		lines = append(lines, fmt.Sprintf("%s (%s): %s, %d away. %s",
+			mob.Name(), side, relativeTo(from, loc), dist(mob), describeMob(mob)))
+	}
+	return lines
+}
+
+// steadyBG is a mob's BG color without the blinking, for accessible mode,
+// or nil if it doesn't have one. It sticks to the first one, which is dark red for low HP.
+func (m *Mob) steadyBG() Color {
+	if m.Dead() || len(m.stats.BGs) == 0 {
+		return nil
+	}
+	return m.stats.BGs[0]
+}
+
+// steadyGlyph is a mob's glyph with its steady BG color, see steadyBG.
+func (m *Mob) steadyGlyph() Glyph {
+	glyph := m.Glyph()
+	if bg := m.steadyBG(); bg != nil {
+		glyph.BG = bg
+	}
+	return glyph
+}
+
+// mobGlyph is how m should be drawn for this session: without the blinking in accessible mode.
+// Anything that draws a mob's glyph on screen should go through here.
+func (sesh *Sesh) mobGlyph(m *Mob) Glyph {
+	if sesh.accessible {
+		return m.steadyGlyph()
+	}
+	return m.Glyph()
+}
+
+// statusLine is m's status line for this session, see mobGlyph.
+func (sesh *Sesh) statusLine(m *Mob, short bool) []Glyph {
+	return m.statusLine(short, sesh.mobGlyph(m))
+}
+
+// needsBlink returns true if anyone is watching outside of accessible mode, so BG colors should keep blinking.
+func (w *World) needsBlink() bool {
+	for sesh := range w.seshes {
+		if !sesh.accessible {
+			return true
+		}
+	}
+	return false
+}
+
+// unblink turns off blinking text for accessible mode.
+func unblink(scr [][]Glyph) {
+	for y := range scr {
+		for x := range scr[y] {
+			scr[y][x].Blink = false
+		}
+	}
+}
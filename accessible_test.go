@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessible(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	knight := spawn(w, 38, 13, &Mob{name: "Knight", team: PlayerTeam, base: Stats{Move: 4}, weapon: weaponSword})
+	kobold := spawn(w, 41, 11, &Mob{name: "Kobold", team: 1, base: Stats{Move: 4}, weapon: weaponSword})
+	kobold.hp = 4
+	kobold.refreshStats(w)
+	w.up = knight
+	w.turn = 3
+
+	r.Equal("3 east, 2 north", relativeTo(knight.Loc(), kobold.Loc()))
+	r.Equal("here", relativeTo(knight.Loc(), knight.Loc()))
+	r.Equal([]string{
+		"Knight (ally): here, 0 away. HP 20 of 20, facing north.",
+		"Kobold (enemy): 3 east, 2 north, 5 away. HP 4 of 20, badly hurt, facing south.",
+	}, nearbyUnits(w, PlayerTeam, knight.Loc()))
+
+	conn := &testConn{}
+	sesh := NewSesh(conn, w)
+	gw := &GameWindow{World: w, Sesh: sesh, Team: PlayerTeam, Map: m}
+	sesh.win = gw
+	sesh.PushWindow(gw)
+	sesh.SetAccessible(true)
+	r.Contains(conn.String(), DisableMouseReporting)
+
+	// turns and the cursor are announced
+	sesh.refresh()
+	r.Equal("Turn 3: Knight's turn (yours), at 38, 13. HP 20 of 20, facing north.", PlainText(gw.Msgs[len(gw.Msgs)-1]))
+	gw.Input("q")
+	sesh.TopWindow().Mouseover(Coords{41, 11})
+	sesh.refresh()
+	r.Contains(PlainText(gw.Msgs[len(gw.Msgs)-1]), "41, 11, 3 east, 2 north: Kobold (enemy) on ")
+	n := len(gw.Msgs)
+	sesh.refresh()
+	r.Len(gw.Msgs, n)
+
+	// log lines lose their colors
+	sesh.Send(Concat(kobold.NameColored(), " attacks!"))
+	r.Equal(GlyphsOf("Kobold attacks!"), gw.Msgs[len(gw.Msgs)-1])
+
+	// no blinking, so low HP stays red even with other buffs to show
+	kobold.ApplyBuff(w, buffHaste(3), nil)
+	r.Contains(describeMob(kobold), "badly hurt, facing south; haste")
+	w.seshes = map[*Sesh]struct{}{sesh: {}}
+	r.Equal(int64(-1), kobold.NextTick(w, 0))
+	kobold.Tick(w, blinkTicks)
+	r.Equal(ColorDarkRed, kobold.Glyph().BG)
+
+	// still steady when someone else wants the blinking
+	other := NewSesh(&testConn{}, w)
+	w.seshes[other] = struct{}{}
+	r.Equal(int64(blinkTicks*2), kobold.NextTick(w, blinkTicks))
+	kobold.Tick(w, blinkTicks*2)
+	r.NotEqual(ColorDarkRed, kobold.Glyph().BG)
+	scr := blankScreen(80, 27)
+	gw.Render(scr)
+	r.Equal(ColorDarkRed, scr[11][41].BG)
+	r.Equal(ColorDarkRed, sesh.statusLine(kobold, true)[1].BG)
+	r.Equal(ColorDarkRed, sesh.mobGlyph(kobold).BG)
+}
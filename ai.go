@@ -0,0 +1,702 @@
+package main
+
+import (
+	"sort"
+)
+
+// Role is the job a unit does for its team, which the AI takes into account when positioning.
+type Role int
+
+const (
+	RoleAuto   Role = iota // figure it out from the unit's weapon and spells
+	RoleMelee              // gets up close and hits things
+	RoleTank               // gets in the way and soaks up hits
+	RoleRanged             // keeps its distance and shoots
+	RoleHealer             // stays back and keeps the team alive
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleMelee:
+		return "melee"
+	case RoleTank:
+		return "tank"
+	case RoleRanged:
+		return "ranged"
+	case RoleHealer:
+		return "healer"
+	}
+	return "auto"
+}
+
+// Personality tunes how the AI weighs its options.
+// The weights are percents, 100 being normal.
+type Personality struct {
+	Name       string
+	Role       Role
+	Aggression int // how much it wants to deal damage and close in
+	Caution    int // how much it fears the damage it could take next turn
+	Focus      int // how much it likes finishing off the wounded
+	Flee       int // runs away when its HP drops below this percent, 0 = never
+}
+
+var (
+	personalityBalanced   = &Personality{Name: "balanced", Aggression: 100, Caution: 50, Focus: 100}
+	personalityAggressive = &Personality{Name: "aggressive", Aggression: 150, Caution: 10, Focus: 60}
+	personalityCowardly   = &Personality{Name: "cowardly", Aggression: 70, Caution: 150, Focus: 100, Flee: 60}
+	personalityCunning    = &Personality{Name: "cunning", Aggression: 100, Caution: 80, Focus: 150}
+	personalityGuardian   = &Personality{Name: "guardian", Role: RoleTank, Aggression: 100, Caution: 20, Focus: 80}
+	personalitySniper     = &Personality{Name: "sniper", Role: RoleRanged, Aggression: 100, Caution: 100, Focus: 120}
+	personalityBerserk    = &Personality{Name: "berserk", Aggression: 200}
+	personalityDefensive  = &Personality{Name: "defensive", Aggression: 70, Caution: 150, Focus: 100, Flee: 30}
+	personalitySupport    = &Personality{Name: "support", Role: RoleHealer, Aggression: 80, Caution: 100, Focus: 100}
+)
+
+// Stance is how a player's unit behaves when the AI is in control of it.
+type Stance int
+
+const (
+	StanceManual     Stance = iota // the player is in control
+	StanceAggressive               // charges in
+	StanceDefensive                // plays it safe and retreats when hurt
+	StanceSupport                  // hangs back, heals and buffs
+	stanceCount
+)
+
+func (s Stance) String() string {
+	switch s {
+	case StanceAggressive:
+		return "aggressive"
+	case StanceDefensive:
+		return "defensive"
+	case StanceSupport:
+		return "support"
+	}
+	return "manual"
+}
+
+// Next returns the stance after this one, for cycling through them.
+func (s Stance) Next() Stance {
+	return (s + 1) % stanceCount
+}
+
+func (s Stance) personality() *Personality {
+	switch s {
+	case StanceAggressive:
+		return personalityAggressive
+	case StanceDefensive:
+		return personalityDefensive
+	case StanceSupport:
+		return personalitySupport
+	}
+	return nil
+}
+
+// AIControlled returns true if the AI takes this unit's turns instead of the player:
+// monsters, berserk units, and the player's units with a stance set or in auto-battle mode.
+func (m *Mob) AIControlled(w *World) bool {
+	return m.Team() != PlayerTeam || m.Berserk() || m.stance != StanceManual || w.autoBattle
+}
+
+// Personality returns how this unit behaves under AI control.
+func (m *Mob) Personality() *Personality {
+	switch {
+	case m.Berserk():
+		return personalityBerserk
+	case m.Team() == PlayerTeam && m.stance != StanceManual:
+		return m.stance.personality()
+	case m.personality == nil:
+		return personalityBalanced
+	}
+	return m.personality
+}
+
+// Role returns this unit's job, going by its personality, spells and weapon.
+func (m *Mob) Role() Role {
+	if role := m.Personality().Role; role != RoleAuto {
+		return role
+	}
+	for _, spell := range m.Spells() {
+		if spell.Damage.Type == DamageHealing {
+			return RoleHealer
+		}
+	}
+	if wep := m.Weapon(); wep.Magic || wep.Range > 1 {
+		return RoleRanged
+	}
+	return RoleMelee
+}
+
+const (
+	killValue   = 10  // how much the AI likes killing something, on top of the damage
+	focusValue  = 5   // how much the AI likes taking a big chunk out of what's left of a target's HP
+	allyFire    = 1.5 // how much worse it is to hurt an ally than it is good to hurt an enemy
+	buffValue   = 6   // how much the AI likes putting a status effect on someone
+	chargeValue = 0.7 // charged spells might miss by the time they go off
+	mpValue     = 0.1 // how much the AI cares about saving MP
+	trapPenalty = 8   // for ending a turn on a trap we know about
+	moveValue   = 0.05
+)
+
+// helpfulBuffs are the buffs the AI casts on its friends instead of its enemies.
+var helpfulBuffs = map[string]bool{
+	"haste":   true,
+	"regen":   true,
+	"protect": true,
+	"renew":   true,
+}
+
+// aiPlan is one thing a unit could do with its turn: move somewhere, then maybe attack.
+type aiPlan struct {
+	path   []Loc // empty = stay put
+	dest   Loc
+	act    bool
+	weapon Weapon
+	spell  bool
+	aim    Loc  // where to aim the attack
+	target *Mob // who's at the aim, for physical attacks
+	score  float64
+}
+
+// threat is someone who might attack us next turn.
+type threat struct {
+	mob   *Mob
+	reach int     // how far away they can hit from
+	dmg   float64 // how much they'd do
+}
+
+// EnemyAIState runs a turn for a unit under AI control: monsters, and berserk or charmed units.
+// It scores every combination of where to move, what to do and who to do it to, and goes with the best.
+type EnemyAIState struct {
+	self  *Mob
+	plan  *aiPlan
+	moved bool
+	acted bool
+
+	// in hard mode, where the plan comes from when it's done searching
+	searching chan *aiPlan
+
+	// figured out once per turn
+	enemies []*Mob
+	allies  []*Mob
+	threats []threat
+	dists   map[*Mob]*PathMap
+	fcs     map[fcKey]Forecast
+}
+
+// HandOffState starts an EnemyAIState for a player's unit that was switched to AI control partway through its turn,
+// as long as it's still that unit's turn by the time everything ahead of it on the state stack is done.
+type HandOffState struct {
+	self  *Mob
+	moved bool
+	acted bool
+}
+
+func (h *HandOffState) Run(w *World) bool {
+	if w.Up() == h.self && !h.self.Dead() && h.self.AIControlled(w) {
+		w.push <- &EnemyAIState{
+			self:  h.self,
+			moved: h.moved,
+			acted: h.acted,
+		}
+	}
+	return true
+}
+
+type fcKey struct {
+	target *Mob
+	weapon string
+	flank  Flank
+}
+
+func (ai *EnemyAIState) Run(w *World) bool {
+	if ai.self.Dead() {
+		w.NextTurn()
+		return true
+	}
+
+	if ai.plan == nil {
+		plan, ok := ai.decide(w)
+		if !ok {
+			// still thinking
+			return false
+		}
+		ai.plan = plan
+		if len(ai.plan.path) > 0 {
+			ai.moved = true
+			w.push <- &MoveState{Obj: ai.self, Path: ai.plan.path}
+			return false
+		}
+	}
+
+	if ai.plan.act && ai.self.CanAct() {
+		ai.act(w, ai.plan)
+	}
+
+	ai.self.FaceNearestEnemy(w)
+	ai.self.FinishTurn(w, ai.moved, ai.acted)
+	w.pushBottom <- NextTurnState{}
+	return true
+}
+
+// act carries out the attack part of plan, if it still makes sense after moving.
+func (ai *EnemyAIState) act(w *World, plan *aiPlan) {
+	loc := ai.self.Loc()
+	m := w.Map(loc.Map)
+	wep := plan.weapon
+	if plan.spell && !ai.self.CanCast(wep) {
+		return
+	}
+	var targets []*Mob
+	var hitlocs, projpath []Loc
+	if wep.Magic {
+		if !inReach(loc, plan.aim, wep) {
+			return
+		}
+		if wep.Charge > 0 {
+			w.StartCharge(ai.self, wep, plan.aim)
+			ai.acted = true
+			return
+		}
+		targets, hitlocs = findTargets(plan.aim, m, true, wep.HitboxSize, wep.Hitbox)
+		_, _, projpath = m.Raycast(loc, plan.aim, true)
+	} else {
+		if plan.target == nil || plan.target.Dead() || !ai.self.CanAttack(w, plan.target, wep) {
+			return
+		}
+		if wep.Charge > 0 {
+			w.StartCharge(ai.self, wep, plan.target.Loc())
+			ai.acted = true
+			return
+		}
+		t, _, path := m.Raycast(loc, plan.target.Loc(), false)
+		targets = []*Mob{t}
+		projpath = path
+	}
+	if plan.spell {
+		w.Broadcast(
+			ai.self.NameColored(),
+			" casts ",
+			GlyphsOf(wep.Name, StyleBold),
+			"!",
+		)
+	}
+	w.push <- &AttackState{
+		Char:     ai.self,
+		Weapon:   wep,
+		HitLocs:  hitlocs,
+		ProjPath: projpath,
+		Targets:  targets,
+	}
+	ai.acted = true
+}
+
+// decide comes up with the plan for this turn. In hard mode, it starts searching in the background
+// and returns false until the search is done.
+func (ai *EnemyAIState) decide(w *World) (*aiPlan, bool) {
+	if ai.searching != nil {
+		select {
+		case plan := <-ai.searching:
+			return plan, true
+		default:
+			return nil, false
+		}
+	}
+	if w.thinkTime == 0 || ai.self.Team() == PlayerTeam {
+		return ai.think(w), true
+	}
+	plans := ai.plans(w)
+	if len(plans) == 1 {
+		return plans[0], true
+	}
+	sim := newSimBattle(w, ai)
+	choices := sim.choices(w, ai, plans)
+	self := -1
+	for i, u := range sim.units {
+		if u.mob == ai.self {
+			self = i
+		}
+	}
+	budget := w.thinkTime
+	ai.searching = make(chan *aiPlan, 1)
+	go func() {
+		ai.searching <- sim.search(self, choices, budget)
+		w.Wake()
+	}()
+	return nil, false
+}
+
+// Idle returns true while the search is still going, so the world can sleep until it's done.
+func (ai *EnemyAIState) Idle() bool {
+	return ai.plan == nil && ai.searching != nil && len(ai.searching) == 0
+}
+
+// think comes up with the best plan for this turn.
+func (ai *EnemyAIState) think(w *World) *aiPlan {
+	return ai.plans(w)[0]
+}
+
+// plans returns every plan worth considering for this turn, best first.
+func (ai *EnemyAIState) plans(w *World) []*aiPlan {
+	self := ai.self
+	loc := self.Loc()
+	m := w.Map(loc.Map)
+	ai.sizeUp(w)
+
+	canMove := self.CanMove() && !ai.moved
+	canAct := self.CanAct() && !ai.acted
+	paths := m.Flood(loc, -1, self)
+
+	stay := &aiPlan{dest: loc, path: []Loc{}}
+	if len(ai.enemies) == 0 {
+		// in fog of war, go look for them
+		if canMove {
+			stay.path = ai.search(w, paths)
+		}
+		return []*aiPlan{stay}
+	}
+
+	var plans []*aiPlan
+	consider := func(plan *aiPlan) {
+		plans = append(plans, plan)
+	}
+
+	for y := 0; y < m.Height(); y++ {
+		for x := 0; x < m.Width(); x++ {
+			cost := paths.Cost(x, y)
+			if cost < 0 || (cost > 0 && (!canMove || cost > self.MoveRange())) {
+				continue
+			}
+			dest := Loc{Map: m.Name, X: x, Y: y}
+			pos := ai.positionScore(w, dest, cost)
+			plan := &aiPlan{dest: dest, score: pos}
+			if cost > 0 {
+				plan.path = paths.PathTo(x, y)
+			}
+			consider(plan)
+			if !canAct {
+				continue
+			}
+			for i, wep := range ai.actions() {
+				for _, aim := range ai.aims(w, dest, wep) {
+					value, ok := ai.actionScore(w, dest, wep, aim)
+					if !ok {
+						continue
+					}
+					consider(&aiPlan{
+						path:   plan.path,
+						dest:   dest,
+						act:    true,
+						weapon: wep,
+						spell:  i > 0,
+						aim:    ai.aimAt(dest, aim),
+						target: aim,
+						score:  pos + value,
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		return plans[i].score > plans[j].score
+	})
+	return plans
+}
+
+// sizeUp figures out who's who, and how dangerous they are.
+func (ai *EnemyAIState) sizeUp(w *World) {
+	self := ai.self
+	m := w.Map(self.Loc().Map)
+	ai.enemies, ai.allies, ai.threats = nil, nil, nil
+	ai.dists = make(map[*Mob]*PathMap)
+	ai.fcs = make(map[fcKey]Forecast)
+	taunted := self.tauntedBy != nil && !self.tauntedBy.Dead()
+	for _, obj := range m.Objects {
+		mob, ok := obj.(*Mob)
+		if !ok || mob.Dead() || mob == self {
+			continue
+		}
+		// in fog of war, only go by what we can see
+		if !w.Sees(self.Team(), mob) {
+			continue
+		}
+		if mob.Team() == self.Team() && !self.Berserk() {
+			ai.allies = append(ai.allies, mob)
+			continue
+		}
+		if mob.Team() != self.Team() && mob.CanAct() {
+			wep := mob.Weapon()
+			fc := forecast(w, mob, wep, self)
+			ai.threats = append(ai.threats, threat{
+				mob:   mob,
+				reach: mob.MoveRange() + wep.Range,
+				dmg:   float64(min(max(fc.Avg, 0), self.HP())*fc.Hit) / 100,
+			})
+		}
+		if taunted && mob != self.tauntedBy {
+			continue
+		}
+		ai.enemies = append(ai.enemies, mob)
+		ai.dists[mob] = m.Flood(mob.Loc(), -1, self)
+	}
+}
+
+// actions returns what this unit can do when it acts: its weapon first, then any spells it can cast right now.
+func (ai *EnemyAIState) actions() []Weapon {
+	actions := []Weapon{ai.self.Weapon()}
+	for _, spell := range ai.self.Spells() {
+		if ai.self.CanCast(spell) {
+			actions = append(actions, spell)
+		}
+	}
+	return actions
+}
+
+// aims returns who could be targeted with wep from dest.
+func (ai *EnemyAIState) aims(w *World, dest Loc, wep Weapon) []*Mob {
+	var aims []*Mob
+	for _, group := range [][]*Mob{ai.enemies, ai.allies, {ai.self}} {
+		for _, mob := range group {
+			tloc := ai.aimAt(dest, mob)
+			if wep.Magic {
+				if inReach(dest, tloc, wep) {
+					aims = append(aims, mob)
+				}
+				continue
+			}
+			if mob != ai.self && ai.self.CanAttackFrom(w, dest, mob, wep) {
+				aims = append(aims, mob)
+			}
+		}
+	}
+	return aims
+}
+
+// inReach returns true if a spell cast from loc can be aimed at target.
+func inReach(loc, target Loc, wep Weapon) bool {
+	if wep.Targeting == TargetingCross && loc.X != target.X && loc.Y != target.Y {
+		return false
+	}
+	return abs(loc.X-target.X)+abs(loc.Y-target.Y) <= wep.Range
+}
+
+// actionScore is how good it would be to use wep on aim after moving to dest.
+// It returns false if it wouldn't do anything useful.
+func (ai *EnemyAIState) actionScore(w *World, dest Loc, wep Weapon, aim *Mob) (float64, bool) {
+	self := ai.self
+	targets := ai.targetsOf(w, dest, wep, aim)
+
+	p := self.Personality()
+	aggression, focus := pct(p.Aggression), pct(p.Focus)
+	healing := 1.0
+	if self.Role() == RoleHealer {
+		healing = 1.5
+	}
+
+	score := 0.0
+	useful := false
+	for _, t := range targets {
+		if t.Dead() {
+			continue
+		}
+		fc := ai.forecast(w, dest, wep, t)
+		hit := float64(fc.Hit) / 100
+		enemy := ai.isEnemy(t)
+		switch {
+		case fc.Heals():
+			healed := float64(min(-fc.Avg, t.MaxHP()-t.HP()))
+			if enemy {
+				score -= healed
+			} else if healed > 0 {
+				score += healed * healing
+				useful = true
+			}
+		case fc.Avg > 0:
+			dmg := float64(min(fc.Avg, t.HP())) * hit
+			kill := float64(fc.Kill) / 100 * killValue
+			if enemy {
+				score += dmg*aggression + (kill+dmg/float64(max(t.HP(), 1))*focusValue)*focus
+				useful = true
+			} else {
+				score -= (dmg + kill) * allyFire
+			}
+		}
+		for _, buff := range fc.Buffs {
+			if t.HasBuff(buff) {
+				continue
+			}
+			switch helpful := helpfulBuffs[buff]; {
+			case helpful == enemy:
+				score -= buffValue * hit
+			case enemy:
+				score += buffValue * hit * aggression
+				useful = true
+			default:
+				score += buffValue * hit
+				useful = true
+			}
+		}
+	}
+	if wep.Charge > 0 {
+		score *= chargeValue
+	}
+	score -= float64(wep.MPCost) * mpValue
+	return score, useful && score > 0
+}
+
+// targetsOf returns who would get hit by using wep on aim after moving to dest.
+func (ai *EnemyAIState) targetsOf(w *World, dest Loc, wep Weapon, aim *Mob) []*Mob {
+	if !wep.Magic {
+		return []*Mob{aim}
+	}
+	aimLoc := ai.aimAt(dest, aim)
+	targets, _ := findTargets(aimLoc, w.Map(dest.Map), true, wep.HitboxSize, wep.Hitbox)
+	// we'll be standing somewhere else by then
+	targets = removeMob(targets, ai.self)
+	if dist := abs(dest.X-aimLoc.X) + abs(dest.Y-aimLoc.Y); dist <= wep.HitboxSize && (wep.Hitbox != HitboxCross || dest.X == aimLoc.X || dest.Y == aimLoc.Y) {
+		targets = append(targets, ai.self)
+	}
+	return targets
+}
+
+// aimAt returns where to aim at m from dest, which is dest itself when aiming at ourselves.
+func (ai *EnemyAIState) aimAt(dest Loc, m *Mob) Loc {
+	if m == ai.self {
+		return dest
+	}
+	return m.Loc()
+}
+
+// forecast is like forecastFrom, but it only crunches the damage numbers once for each side of each target.
+func (ai *EnemyAIState) forecast(w *World, from Loc, wep Weapon, target *Mob) Forecast {
+	key := fcKey{target: target, weapon: wep.Name, flank: attackFlank(ai.self, from, target, wep)}
+	fc, ok := ai.fcs[key]
+	if !ok {
+		fc = forecastFrom(w, ai.self, from, wep, target)
+		ai.fcs[key] = fc
+		return fc
+	}
+	hit := HitChance(w, ai.self, from, target, wep)
+	if fc.Hit > 0 {
+		fc.Kill = fc.Kill * hit / fc.Hit
+	}
+	fc.Hit = hit
+	return fc
+}
+
+// positionScore is how good it would be to end the turn at dest, having spent cost to get there.
+func (ai *EnemyAIState) positionScore(w *World, dest Loc, cost int) float64 {
+	self := ai.self
+	p := self.Personality()
+	role := self.Role()
+	aggression, caution := pct(p.Aggression), pct(p.Caution)
+	hurt := 1 - float64(self.HP())/float64(max(self.MaxHP(), 1))
+	fleeing := p.Flee > 0 && self.HP()*100 < self.MaxHP()*p.Flee
+	if role == RoleTank {
+		caution *= 0.3
+	}
+	if fleeing {
+		caution *= 2
+	}
+
+	score := -float64(cost) * moveValue
+
+	// how much could we get hurt next turn?
+	danger := 0.0
+	for _, t := range ai.threats {
+		tloc := t.mob.Loc()
+		if abs(tloc.X-dest.X)+abs(tloc.Y-dest.Y) <= t.reach {
+			danger += t.dmg
+		}
+	}
+	score -= danger * caution * (1 + hurt)
+
+	// how far from the action are we?
+	near, walk := -1, -1
+	for _, enemy := range ai.enemies {
+		eloc := enemy.Loc()
+		d := abs(eloc.X-dest.X) + abs(eloc.Y-dest.Y)
+		if near == -1 || d < near {
+			near = d
+		}
+		steps := ai.dists[enemy].Cost(dest.X, dest.Y)
+		if steps < 0 {
+			steps = d * 3
+		}
+		if walk == -1 || steps < walk {
+			walk = steps
+		}
+	}
+	rng := self.Weapon().Range
+	switch {
+	case fleeing:
+		score += float64(near)
+	case role == RoleRanged:
+		if near < rng {
+			score -= float64(rng-near) * 0.5
+		} else {
+			score -= float64(walk-rng) * 0.3 * aggression
+		}
+	case role == RoleHealer:
+		closest := -1
+		for _, ally := range ai.allies {
+			aloc := ally.Loc()
+			if d := abs(aloc.X-dest.X) + abs(aloc.Y-dest.Y); closest == -1 || d < closest {
+				closest = d
+			}
+		}
+		if closest > 0 {
+			score -= float64(closest) * 0.3
+		}
+		if near < 3 {
+			score -= float64(3 - near)
+		}
+	default:
+		score -= float64(walk) * 0.3 * aggression
+	}
+
+	// stay off known traps
+	if trap := w.Map(dest.Map).TrapAt(dest); trap != nil && trap.Harmful() && !trap.Hidden() && trap.affects(self) {
+		score -= trapPenalty
+	}
+	return score
+}
+
+// isEnemy returns true if the AI wants to hurt m.
+func (ai *EnemyAIState) isEnemy(m *Mob) bool {
+	if m == ai.self {
+		return false
+	}
+	return m.Team() != ai.self.Team() || ai.self.Berserk()
+}
+
+// search returns a path toward the closest place an enemy was last seen,
+// for when there's nobody in sight in fog of war mode.
+func (ai *EnemyAIState) search(w *World, paths *PathMap) []Loc {
+	v := w.Vision(ai.self.Team())
+	if v == nil {
+		return nil
+	}
+	var best []Loc
+	for _, loc := range v.lastSeen {
+		path := paths.NextTo(loc)
+		if len(path) > 0 && (best == nil || len(path) < len(best)) {
+			best = path
+		}
+	}
+	return paths.Truncate(best, ai.self.MoveRange())
+}
+
+func removeMob(mobs []*Mob, m *Mob) []*Mob {
+	kept := mobs[:0]
+	for _, mob := range mobs {
+		if mob != m {
+			kept = append(kept, mob)
+		}
+	}
+	return kept
+}
+
+func pct(n int) float64 {
+	return float64(n) / 100
+}
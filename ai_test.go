@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnemyAI(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	dist := func(a, b Loc) int {
+		return abs(a.X-b.X) + abs(a.Y-b.Y)
+	}
+
+	bear := spawn(w, 38, 13, &Mob{team: 1, base: Stats{Move: 4}, weapon: weaponBite})
+	bear.personality = personalityAggressive
+	healthy := spawn(w, 36, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4}, weapon: weaponBite})
+	wounded := spawn(w, 42, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4}, weapon: weaponBite})
+	wounded.hp = 1
+
+	// goes for the kill
+	plan := (&EnemyAIState{self: bear}).think(w)
+	r.True(plan.act)
+	r.Equal(wounded, plan.target)
+	r.Equal(1, dist(plan.dest, wounded.Loc()))
+
+	// cowards run away when hurt
+	m.Remove(bear)
+	rabbit := spawn(w, 38, 13, &Mob{team: 1, base: Stats{Move: 4}, weapon: weaponBite})
+	rabbit.personality = personalityCowardly
+	rabbit.hp = 5
+	plan = (&EnemyAIState{self: rabbit}).think(w)
+	r.False(plan.act)
+	r.Greater(dist(plan.dest, healthy.Loc()), dist(rabbit.Loc(), healthy.Loc()))
+
+	// but not when they're fine
+	rabbit.hp = rabbit.maxHP
+	plan = (&EnemyAIState{self: rabbit}).think(w)
+	r.True(plan.act)
+}
+
+func TestEnemySpells(t *testing.T) {
+	r := require.New(t)
+
+	w, _ := newTestWorld(t, "oneroom")
+
+	shaman := spawn(w, 38, 13, &Mob{team: 1, base: Stats{Move: 4}, weapon: weaponBite})
+	shaman.weapon = weaponStaff
+	shaman.spells = []Weapon{spellHeal, spellSlow}
+	shaman.maxMP = 20
+	shaman.mp = 20
+	r.Equal(RoleHealer, shaman.Role())
+	friend := spawn(w, 36, 10, &Mob{team: 1, base: Stats{Move: 4}, weapon: weaponBite})
+	friend.hp = 5
+	spawn(w, 46, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4}, weapon: weaponBite})
+
+	// heals the wounded
+	plan := (&EnemyAIState{self: shaman}).think(w)
+	r.True(plan.act)
+	r.True(plan.spell)
+	r.Equal(spellHeal.Name, plan.weapon.Name)
+
+	// slows down enemies once everyone's fine
+	friend.hp = friend.maxHP
+	plan = (&EnemyAIState{self: shaman}).think(w)
+	r.True(plan.act)
+	r.Equal(spellSlow.Name, plan.weapon.Name)
+
+	// but not without the MP for it
+	shaman.mp = 0
+	plan = (&EnemyAIState{self: shaman}).think(w)
+	r.False(plan.spell)
+}
+
+func TestStance(t *testing.T) {
+	r := require.New(t)
+
+	w := &World{objects: make(map[ID]Object)}
+	knight := generateUnit("Knight")
+	r.False(knight.AIControlled(w))
+	r.Equal(RoleMelee, knight.Role())
+
+	knight.stance = StanceSupport
+	r.True(knight.AIControlled(w))
+	r.Equal(RoleHealer, knight.Role())
+
+	knight.stance = knight.stance.Next()
+	r.Equal(StanceManual, knight.stance)
+	w.autoBattle = true
+	r.True(knight.AIControlled(w))
+	r.Equal(personalityBalanced, knight.Personality())
+}
+
+func TestHandOffWhileBusy(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	knight := spawn(w, 38, 13, generateUnit("Knight"))
+	w.up = knight
+
+	sesh := NewSesh(&testConn{}, w)
+	gw := &GameWindow{World: w, Sesh: sesh, Team: PlayerTeam, Map: m, moved: true}
+	sesh.win = gw
+
+	// switched to AI while still walking
+	walk := &MoveState{Obj: knight, Path: []Loc{{Map: m.Name, X: 38, Y: 12}, {Map: m.Name, X: 38, Y: 11}}}
+	w.state = []StateAction{walk}
+	knight.stance = StanceAggressive
+	gw.handOff()
+	r.False(gw.myTurn())
+	r.Len(w.push, 0)
+	w.state = append([]StateAction{<-w.pushBottom}, w.state...)
+
+	// the AI picks up once the move is done, like World.step would
+	for len(w.state) > 0 && len(w.push) == 0 {
+		if w.state[len(w.state)-1].Run(w) {
+			w.state = w.state[:len(w.state)-1]
+		}
+	}
+	r.Equal(11, knight.Loc().Y)
+	ai := (<-w.push).(*EnemyAIState)
+	r.Equal(knight, ai.self)
+	r.True(ai.moved)
+
+	// unless the turn's over by then
+	w.up = nil
+	r.True((&HandOffState{self: knight}).Run(w))
+	r.Len(w.push, 0)
+}
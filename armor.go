@@ -8,16 +8,19 @@ type Armor struct {
 	Name       string
 	Defense    int
 	MPRecovery int
+	Evasion    int // percent
 	Value      int
 }
 
 var armorLeather = Armor{
 	Name:    "tunic",
 	Defense: 1,
+	Evasion: 5,
 }
 var armorLeather2 = Armor{
 	Name:    "jerkin",
 	Defense: 2,
+	Evasion: 10,
 	Value:   1,
 }
 
@@ -53,6 +56,7 @@ var armorChainmail = Armor{
 var armorPlate = Armor{
 	Name:    "platemail",
 	Defense: 5,
+	Evasion: -10,
 	Value:   3,
 }
 
@@ -67,5 +71,11 @@ func (a Armor) String() string {
 		}
 		info += fmt.Sprintf("%dMP/t", a.MPRecovery)
 	}
+	if a.Evasion != 0 {
+		if info != "" {
+			info += " "
+		}
+		info += fmt.Sprintf("%+dEV", a.Evasion)
+	}
 	return fmt.Sprintf("%s (%s)", a.Name, info)
 }
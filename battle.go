@@ -114,14 +114,23 @@ var classBase = map[Class]Mob{
 		spells: []Weapon{
 			spellTaunt,
 			spellCharge,
+			spellShieldBash,
 		},
+		affinities: Affinities{
+			ElementSlash: AffinityResist,
+		},
+		reaction: Reaction{Type: ReactionCounter},
 	},
 	"Archer": Mob{
 		class: "Archer",
 		glyph: GlyphOf('@'),
 		base: Stats{
-			Speed: 6,
-			Move:  7,
+			Speed:      6,
+			Move:       7,
+			Jump:       3,
+			Accuracy:   10,
+			Perception: 3,
+			Vision:     9,
 		},
 		maxHP:  15,
 		maxMP:  10,
@@ -130,7 +139,9 @@ var classBase = map[Class]Mob{
 		spells: []Weapon{
 			spellCripple,
 			spellPoisonShot,
+			spellCaltrops,
 		},
+		reaction: Reaction{Type: ReactionDodge, Chance: 20},
 	},
 	"Wizard": Mob{
 		class: "Wizard",
@@ -146,6 +157,11 @@ var classBase = map[Class]Mob{
 			spellFireball,
 		},
 		armor: armorRobe,
+		affinities: Affinities{
+			ElementFire: AffinityResist,
+			ElementIce:  AffinityResist,
+		},
+		reaction: Reaction{Type: ReactionMPOnHit, Amount: 3},
 	},
 	"Priest": Mob{
 		class: "Priest",
@@ -163,6 +179,10 @@ var classBase = map[Class]Mob{
 			spellRenew,
 		},
 		armor: armorRobe,
+		affinities: Affinities{
+			ElementHoly: AffinityResist,
+		},
+		reaction: Reaction{Type: ReactionHeal, Amount: 3},
 	},
 }
 
@@ -209,26 +229,40 @@ var monstersByLevel = [][]Mob{
 			},
 			maxHP:  10,
 			weapon: weaponLick,
+			affinities: Affinities{
+				ElementBlunt:  AffinityResist,
+				ElementPoison: AffinityAbsorb,
+			},
 		},
 		Mob{
 			name:  "rabbit",
 			glyph: GlyphOf('w'),
 			base: Stats{
-				Speed: 8,
-				Move:  4,
+				Speed:   8,
+				Move:    4,
+				Evasion: 15,
 			},
-			maxHP:  8,
-			weapon: weaponBite,
+			maxHP:       8,
+			weapon:      weaponBite,
+			personality: personalityCowardly,
+			reaction:    Reaction{Type: ReactionDodge, Chance: 20},
 		},
 		Mob{
 			name:  "little bird",
 			glyph: GlyphOf('b'),
 			base: Stats{
-				Speed: 8,
-				Move:  5,
+				Speed:   8,
+				Move:    5,
+				Evasion: 20,
+			},
+			maxHP:       6,
+			weapon:      weaponPeck,
+			personality: personalityCowardly,
+			flying:      true,
+			affinities: Affinities{
+				ElementPierce:    AffinityWeak,
+				ElementLightning: AffinityWeak,
 			},
-			maxHP:  6,
-			weapon: weaponPeck,
 		},
 		Mob{
 			name:  "pig",
@@ -251,6 +285,9 @@ var monstersByLevel = [][]Mob{
 			},
 			maxHP:  15,
 			weapon: weaponShank,
+			affinities: Affinities{
+				ElementHoly: AffinityWeak,
+			},
 		},
 		Mob{
 			name:  "big Kobold",
@@ -259,18 +296,43 @@ var monstersByLevel = [][]Mob{
 				Speed: 4,
 				Move:  6,
 			},
-			maxHP:  20,
-			weapon: weaponShank,
+			maxHP:       20,
+			weapon:      weaponShank,
+			personality: personalityGuardian,
+			affinities: Affinities{
+				ElementHoly: AffinityWeak,
+			},
+			reaction: Reaction{Type: ReactionCounter, Chance: 50},
+		},
+		Mob{
+			name:  "Kobold shaman",
+			glyph: GlyphOf('k'),
+			base: Stats{
+				Speed: 5,
+				Move:  4,
+			},
+			maxHP:  12,
+			maxMP:  20,
+			weapon: weaponStaff,
+			spells: []Weapon{
+				spellHeal,
+				spellSlow,
+			},
+			affinities: Affinities{
+				ElementHoly: AffinityWeak,
+			},
 		},
 		Mob{
 			name:  "jackal",
 			glyph: GlyphOf('d'),
 			base: Stats{
-				Speed: 8,
-				Move:  6,
+				Speed:   8,
+				Move:    6,
+				Evasion: 10,
 			},
-			maxHP:  10,
-			weapon: weaponBite,
+			maxHP:       10,
+			weapon:      weaponBite,
+			personality: personalityCunning,
 		},
 		Mob{
 			name:  "sewer rat",
@@ -279,8 +341,12 @@ var monstersByLevel = [][]Mob{
 				Speed: 10,
 				Move:  4,
 			},
-			maxHP:  8,
-			weapon: weaponBite,
+			maxHP:       8,
+			weapon:      weaponBite,
+			personality: personalityCowardly,
+			affinities: Affinities{
+				ElementPoison: AffinityImmune,
+			},
 		},
 	},
 	{
@@ -288,21 +354,28 @@ var monstersByLevel = [][]Mob{
 			name:  "archer",
 			glyph: GlyphOf('@'),
 			base: Stats{
-				Speed: 5,
-				Move:  5,
+				Speed:    5,
+				Move:     5,
+				Accuracy: 10,
 			},
-			maxHP:  15,
-			weapon: weaponBow,
+			maxHP:       15,
+			weapon:      weaponBow,
+			personality: personalitySniper,
 		},
 		Mob{
 			name:  "ninja",
 			glyph: GlyphOf('@'),
 			base: Stats{
-				Speed: 6,
-				Move:  6,
+				Speed:   6,
+				Move:    6,
+				Evasion: 15,
 			},
-			maxHP:  15,
-			weapon: weaponSword,
+			maxHP:       15,
+			maxMP:       10,
+			weapon:      weaponKusarigama,
+			spells:      []Weapon{spellBlind},
+			personality: personalityCunning,
+			reaction:    Reaction{Type: ReactionDodge, Chance: 30},
 		},
 		Mob{
 			name:  "samurai",
@@ -311,18 +384,23 @@ var monstersByLevel = [][]Mob{
 				Speed: 4,
 				Move:  4,
 			},
-			maxHP:  20,
-			weapon: weaponSpear,
+			maxHP:       20,
+			weapon:      weaponSpear,
+			personality: personalityGuardian,
+			reaction:    Reaction{Type: ReactionFirstStrike},
 		},
 		Mob{
 			name:  "fox",
 			glyph: GlyphOf('f'),
 			base: Stats{
-				Speed: 12,
-				Move:  4,
+				Speed:   12,
+				Move:    4,
+				Evasion: 15,
 			},
-			maxHP:  10,
-			weapon: weaponBite,
+			maxHP:       10,
+			weapon:      weaponBite,
+			personality: personalityCunning,
+			reaction:    Reaction{Type: ReactionDodge, Chance: 25},
 		},
 	},
 	{
@@ -333,8 +411,12 @@ var monstersByLevel = [][]Mob{
 				Speed: 5,
 				Move:  5,
 			},
-			maxHP:  20,
-			weapon: weaponPick,
+			maxHP:       20,
+			weapon:      weaponPick,
+			personality: personalityGuardian,
+			affinities: Affinities{
+				ElementFire: AffinityResist,
+			},
 		},
 		Mob{
 			name:  "gnome",
@@ -343,8 +425,9 @@ var monstersByLevel = [][]Mob{
 				Speed: 8,
 				Move:  5,
 			},
-			maxHP:  15,
-			weapon: weaponShank,
+			maxHP:       15,
+			weapon:      weaponShank,
+			personality: personalityCowardly,
 		},
 		Mob{
 			name:  "gnome lord",
@@ -353,8 +436,11 @@ var monstersByLevel = [][]Mob{
 				Speed: 8,
 				Move:  4,
 			},
-			maxHP:  20,
-			weapon: weaponSword,
+			maxHP:       20,
+			maxMP:       16,
+			weapon:      weaponSword,
+			spells:      []Weapon{spellHaste},
+			personality: personalityAggressive,
 		},
 		Mob{
 			name:  "horse",
@@ -375,18 +461,26 @@ var monstersByLevel = [][]Mob{
 				Speed: 4,
 				Move:  4,
 			},
-			maxHP:  26,
-			weapon: weaponSwipe,
+			maxHP:       26,
+			weapon:      weaponSwipe,
+			personality: personalityAggressive,
+			affinities: Affinities{
+				ElementBlunt: AffinityResist,
+				ElementFire:  AffinityWeak,
+			},
+			reaction: Reaction{Type: ReactionCounter, Chance: 50},
 		},
 		Mob{
 			name:  "hunter",
 			glyph: GlyphOf('@'),
 			base: Stats{
-				Speed: 5,
-				Move:  6,
+				Speed:    5,
+				Move:     6,
+				Accuracy: 10,
 			},
-			maxHP:  20,
-			weapon: weaponLongbow,
+			maxHP:       20,
+			weapon:      weaponLongbow,
+			personality: personalitySniper,
 		},
 		Mob{
 			name:  "foxhound",
@@ -395,8 +489,9 @@ var monstersByLevel = [][]Mob{
 				Speed: 8,
 				Move:  6,
 			},
-			maxHP:  12,
-			weapon: weaponBite,
+			maxHP:       12,
+			weapon:      weaponBite,
+			personality: personalityAggressive,
 		},
 	},
 	{
@@ -407,8 +502,13 @@ var monstersByLevel = [][]Mob{
 				Speed: 6,
 				Move:  4,
 			},
-			maxHP:  25,
-			weapon: weaponYetiFist,
+			maxHP:       25,
+			weapon:      weaponYetiFist,
+			personality: personalityAggressive,
+			affinities: Affinities{
+				ElementIce:  AffinityImmune,
+				ElementFire: AffinityWeak,
+			},
 		},
 		Mob{
 			name:  "polar bear",
@@ -417,18 +517,30 @@ var monstersByLevel = [][]Mob{
 				Speed: 3,
 				Move:  3,
 			},
-			maxHP:  35,
-			weapon: weaponSwipe,
+			maxHP:       35,
+			weapon:      weaponSwipe,
+			personality: personalityAggressive,
+			affinities: Affinities{
+				ElementIce:  AffinityResist,
+				ElementFire: AffinityWeak,
+			},
+			reaction: Reaction{Type: ReactionCounter, Chance: 50},
 		},
 		Mob{
 			name:  "snow fox",
 			glyph: GlyphOf('S'),
 			base: Stats{
-				Speed: 7,
-				Move:  7,
+				Speed:   7,
+				Move:    7,
+				Evasion: 15,
+			},
+			maxHP:       15,
+			weapon:      weaponSnowFoxBite,
+			personality: personalityCunning,
+			affinities: Affinities{
+				ElementIce:  AffinityResist,
+				ElementFire: AffinityWeak,
 			},
-			maxHP:  15,
-			weapon: weaponSnowFoxBite,
 		},
 	},
 	{
@@ -436,11 +548,21 @@ var monstersByLevel = [][]Mob{
 			name:  "golem",
 			glyph: GlyphOf('&'),
 			base: Stats{
-				Speed: 3,
-				Move:  4,
+				Speed:   3,
+				Move:    4,
+				Evasion: -10,
 			},
-			maxHP:  40,
-			weapon: weaponCrush,
+			maxHP:       40,
+			weapon:      weaponCrush,
+			personality: personalityGuardian,
+			affinities: Affinities{
+				ElementSlash:     AffinityResist,
+				ElementPierce:    AffinityResist,
+				ElementBlunt:     AffinityWeak,
+				ElementLightning: AffinityWeak,
+				ElementPoison:    AffinityImmune,
+			},
+			reaction: Reaction{Type: ReactionCounter},
 		},
 		Mob{
 			name:  "dragon",
@@ -449,8 +571,16 @@ var monstersByLevel = [][]Mob{
 				Speed: 5,
 				Move:  5,
 			},
-			maxHP:  32,
-			weapon: weaponFirebreathing,
+			maxHP:       32,
+			maxMP:       16,
+			weapon:      weaponFirebreathing,
+			spells:      []Weapon{spellMeteor},
+			personality: personalityAggressive,
+			flying:      true,
+			affinities: Affinities{
+				ElementFire: AffinityAbsorb,
+				ElementIce:  AffinityWeak,
+			},
 		},
 		Mob{
 			name:  "archon",
@@ -461,7 +591,17 @@ var monstersByLevel = [][]Mob{
 			},
 			maxHP:  30,
 			maxMP:  100,
-			weapon: spellSmite,
+			weapon: weaponSword,
+			spells: []Weapon{
+				spellSmite,
+				spellHeal2,
+				spellSilence,
+			},
+			affinities: Affinities{
+				ElementHoly:   AffinityAbsorb,
+				ElementPoison: AffinityImmune,
+			},
+			reaction: Reaction{Type: ReactionHeal, Amount: 5},
 		},
 	},
 }
@@ -122,6 +122,34 @@ var classSpells = map[Class][]spellProgression{
 			spell: spellBolt,
 			level: 2,
 		},
+		{
+			spell: spellSleep,
+		},
+		{
+			spell: spellSlow,
+		},
+		{
+			spell: spellSilence,
+			level: 2,
+		},
+		{
+			spell: spellBlind,
+			level: 2,
+		},
+		{
+			spell: spellBerserk,
+			level: 3,
+		},
+		{
+			spell: spellCharm,
+			level: 4,
+		},
+		{
+			spell: spellWard,
+		},
+		{
+			spell: spellGust,
+		},
 	},
 	"Priest": []spellProgression{
 		{
@@ -137,6 +165,31 @@ var classSpells = map[Class][]spellProgression{
 			spell: spellSmite2,
 			level: 3,
 		},
+		{
+			spell: spellProtect,
+		},
+		{
+			spell: spellCleanse,
+		},
+		{
+			spell: spellRegen,
+			level: 2,
+		},
+		{
+			spell: spellHaste,
+			level: 2,
+		},
+		{
+			spell: spellDispel,
+			level: 3,
+		},
+		{
+			spell: spellBarrier,
+		},
+		{
+			spell: spellSanctuary,
+			level: 3,
+		},
 	},
 }
 
@@ -0,0 +1,645 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The bot protocol lets programs play the game as the player's team.
+// Bots send one JSON object per line and get exactly one JSON object back per line:
+//
+//	{"id": 1, "cmd": "state"}
+//	{"id": 1, "ok": true, "state": {...}}
+//
+// Commands:
+//
+//	state                   replies right away with the current state
+//	await                   waits until it's the bot's turn, or the battle is over
+//	start                   starts a new game, from the title screen or after winning or losing
+//	move x y                moves the unit that's up to x, y
+//	attack x y              attacks x, y with the unit's weapon
+//	cast spell x y          casts a spell (by name) at x, y
+//	wait [facing]           ends the turn, optionally facing north, east, south or west
+//	bonus unit              picks which unit (by index into the team) gets its bonus after a battle
+//
+// Everything but state replies once the world is ready for the bot's next decision, like await.
+// Turns don't end on their own after moving and acting: bots have to wait.
+// Moves, attacks and casts are checked against the same rules as GameWindow, and the
+// legal ones are listed in the state when it's the bot's turn.
+// In fog of war, bots can only move to, attack or cast at tiles their team can see.
+
+const (
+	botMaxLog      = 100 // combat log lines to keep between replies
+	botOrderLength = 10  // how many upcoming turns to show
+)
+
+// Bot is a connection to a program playing the game.
+type Bot struct {
+	world *World
+	in    io.Reader
+	out   io.Writer
+
+	// only touched on the world goroutine
+	up      Turner
+	turn    int64
+	moved   bool
+	acted   bool
+	log     []string
+	bonuses []Bonus
+	waiting chan botReply // where to reply once the world is ready for the bot, see World.answerBots
+	held    botReply      // the reply to send there, minus the state
+}
+
+func NewBot(w *World, in io.Reader, out io.Writer) *Bot {
+	return &Bot{world: w, in: in, out: out}
+}
+
+type botRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Cmd    string          `json:"cmd"`
+	X      int             `json:"x"`
+	Y      int             `json:"y"`
+	Spell  string          `json:"spell,omitempty"`
+	Facing string          `json:"facing,omitempty"`
+	Unit   int             `json:"unit"`
+}
+
+type botReply struct {
+	ID    json.RawMessage `json:"id,omitempty"`
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	State *botState       `json:"state,omitempty"`
+}
+
+type botState struct {
+	Phase    string              `json:"phase"` // title, battle, bonus, won or lost
+	Level    int                 `json:"level"`
+	Turn     int64               `json:"turn"`
+	Score    int                 `json:"score"`
+	YourTurn bool                `json:"your_turn"`
+	Up       ID                  `json:"up,omitempty"` // the unit whose turn it is
+	Moved    bool                `json:"moved"`
+	Acted    bool                `json:"acted"`
+	Map      *botMap             `json:"map,omitempty"`
+	Units    []botUnit           `json:"units,omitempty"`
+	Order    []botTurn           `json:"order,omitempty"`
+	Moves    [][2]int            `json:"moves,omitempty"`
+	Attacks  [][2]int            `json:"attacks,omitempty"`
+	Casts    map[string][][2]int `json:"casts,omitempty"`
+	Bonuses  []string            `json:"bonuses,omitempty"`
+	Log      []string            `json:"log,omitempty"`
+}
+
+type botMap struct {
+	Name    string                `json:"name"`
+	Width   int                   `json:"width"`
+	Height  int                   `json:"height"`
+	Tiles   []string              `json:"tiles"`   // one string per row, blank where unexplored
+	Heights [][]int               `json:"heights"` // -1 where unexplored
+	Legend  map[string]botTerrain `json:"legend"`
+}
+
+type botTerrain struct {
+	Name         string `json:"name"`
+	Cost         int    `json:"cost"` // -1 = impassable
+	FlyOnly      bool   `json:"fly_only,omitempty"`
+	Defense      int    `json:"defense,omitempty"`
+	Evasion      int    `json:"evasion,omitempty"`
+	Cover        int    `json:"cover,omitempty"`
+	Hazard       int    `json:"hazard,omitempty"`
+	Destructible bool   `json:"destructible,omitempty"`
+	Use          string `json:"use,omitempty"`
+}
+
+type botUnit struct {
+	ID     ID          `json:"id"`
+	Name   string      `json:"name"`
+	Team   int         `json:"team"`
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+	HP     int         `json:"hp"`
+	MaxHP  int         `json:"max_hp"`
+	MP     int         `json:"mp"`
+	MaxMP  int         `json:"max_mp"`
+	Shield int         `json:"shield,omitempty"`
+	CT     int         `json:"ct"`
+	Speed  int         `json:"speed"`
+	Move   int         `json:"move"`
+	Jump   int         `json:"jump"`
+	Facing string      `json:"facing"`
+	Flying bool        `json:"flying,omitempty"`
+	Weapon botWeapon   `json:"weapon"`
+	Spells []botWeapon `json:"spells,omitempty"`
+	Buffs  []string    `json:"buffs,omitempty"`
+	Dead   bool        `json:"dead,omitempty"`
+}
+
+type botWeapon struct {
+	Name     string `json:"name"`
+	Damage   string `json:"damage,omitempty"`
+	Element  string `json:"element,omitempty"`
+	Range    int    `json:"range"`
+	Magic    bool   `json:"magic,omitempty"`
+	MP       int    `json:"mp,omitempty"`
+	Cooldown int    `json:"cooldown,omitempty"` // turns left
+	Charge   int    `json:"charge,omitempty"`
+}
+
+type botTurn struct {
+	ID   ID     `json:"id"`
+	Name string `json:"name"`
+}
+
+// serveBots starts serving the bot protocol on addr: "stdio", "tcp:host:port" or "unix:/path".
+func serveBots(w *World, addr string) error {
+	if addr == "stdio" {
+		out := os.Stdout
+		// keep stray prints from getting mixed up with the protocol
+		os.Stdout = os.Stderr
+		go func() {
+			if err := NewBot(w, os.Stdin, out).Run(); err != nil {
+				log.Println("bot:", err)
+			}
+		}()
+		return nil
+	}
+	split := strings.SplitN(addr, ":", 2)
+	if len(split) != 2 || (split[0] != "tcp" && split[0] != "unix") {
+		return fmt.Errorf("invalid bot address: %q", addr)
+	}
+	l, err := net.Listen(split[0], split[1])
+	if err != nil {
+		return err
+	}
+	log.Println("serving bots on", addr)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				log.Println("bot:", err)
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := NewBot(w, conn, conn).Run(); err != nil {
+					log.Println("bot:", err)
+				}
+			}()
+		}
+	}()
+	return nil
+}
+
+// Run handles requests until the bot disconnects.
+func (b *Bot) Run() error {
+	b.world.apply <- botJoinAction{bot: b}
+	defer func() {
+		b.world.apply <- botPartAction{bot: b}
+	}()
+
+	scanner := bufio.NewScanner(b.in)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	enc := json.NewEncoder(b.out)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req botRequest
+		var reply botReply
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			reply = botReply{Error: "invalid request: " + err.Error()}
+		} else {
+			reply = b.do(req)
+		}
+		if err := enc.Encode(reply); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// do sends req to the world and waits for the reply.
+func (b *Bot) do(req botRequest) botReply {
+	ch := make(chan botReply, 1)
+	b.world.apply <- botAction{bot: b, req: req, reply: ch}
+	return <-ch
+}
+
+type botJoinAction struct {
+	bot *Bot
+}
+
+func (a botJoinAction) Apply(w *World) {
+	w.bots[a.bot] = struct{}{}
+}
+
+type botPartAction struct {
+	bot *Bot
+}
+
+func (a botPartAction) Apply(w *World) {
+	delete(w.bots, a.bot)
+}
+
+// botAction runs a bot's command on the world goroutine.
+type botAction struct {
+	bot   *Bot
+	req   botRequest
+	reply chan botReply
+}
+
+func (a botAction) Apply(w *World) {
+	b := a.bot
+	b.sync(w)
+	reply := botReply{ID: a.req.ID, OK: true}
+	if err := b.exec(w, a.req); err != nil {
+		reply.OK = false
+		reply.Error = err.Error()
+	}
+	if a.req.Cmd != "state" && !b.ready(w) {
+		b.waiting = a.reply
+		b.held = reply
+		return
+	}
+	b.answer(w, reply, a.reply)
+}
+
+// answer sends reply back to the bot with the current state.
+func (b *Bot) answer(w *World, reply botReply, ch chan botReply) {
+	b.sync(w)
+	reply.State = b.state(w)
+	ch <- reply
+}
+
+// answerBots replies to the bots that were waiting for the world to be ready for them.
+// The world loop calls it whenever something might have changed.
+func (w *World) answerBots() {
+	for b := range w.bots {
+		if b.waiting != nil && b.ready(w) {
+			ch := b.waiting
+			b.waiting = nil
+			b.answer(w, b.held, ch)
+		}
+	}
+}
+
+// sync resets the bot's turn when a new one starts.
+func (b *Bot) sync(w *World) {
+	if w.Up() != b.up || w.turn != b.turn {
+		b.up = w.Up()
+		b.turn = w.turn
+		b.moved = false
+		b.acted = false
+	}
+}
+
+func (b *Bot) exec(w *World, req botRequest) error {
+	switch strings.ToLower(req.Cmd) {
+	case "state", "await":
+		return nil
+	case "start":
+		switch b.phase(w) {
+		case "title":
+		case "won", "lost":
+			w.reset()
+		default:
+			return fmt.Errorf("a game is already in progress")
+		}
+		w.StartBattle(0)
+		return nil
+	case "bonus":
+		if b.phase(w) != "bonus" {
+			return fmt.Errorf("no bonus to pick right now")
+		}
+		bonuses := b.pendingBonuses(w)
+		if req.Unit < 0 || req.Unit >= len(bonuses) || w.player.Units[req.Unit].Dead() {
+			return fmt.Errorf("invalid unit: %d", req.Unit)
+		}
+		w.ApplyBonus(bonuses[req.Unit], w.player.Units[req.Unit])
+		b.bonuses = nil
+		w.StartBattle(w.level + 1)
+		return nil
+	case "move", "attack", "cast", "wait":
+	default:
+		return fmt.Errorf("unknown command: %q", req.Cmd)
+	}
+
+	m, ok := w.Up().(*Mob)
+	if !ok || !b.myTurn(w) {
+		return fmt.Errorf("not your turn")
+	}
+	loc := m.Loc()
+	target := Loc{Map: loc.Map, X: req.X, Y: req.Y}
+	if !strings.EqualFold(req.Cmd, "wait") && !w.CanSee(PlayerTeam, target) {
+		return fmt.Errorf("can't see %d, %d", req.X, req.Y)
+	}
+	switch strings.ToLower(req.Cmd) {
+	case "move":
+		if b.moved || !m.CanMove() {
+			return fmt.Errorf("%s can't move", m.Name())
+		}
+		path := b.paths(w, m).PathTo(req.X, req.Y)
+		if len(path) == 0 {
+			return fmt.Errorf("can't move to %d, %d", req.X, req.Y)
+		}
+		w.push <- &MoveState{Obj: m, Path: path}
+		b.moved = true
+	case "attack":
+		if b.acted || !m.CanAct() {
+			return fmt.Errorf("%s can't act", m.Name())
+		}
+		if err := w.StartAttack(m, m.Weapon(), target); err != nil {
+			return err
+		}
+		b.acted = true
+	case "cast":
+		if b.acted || !m.CanAct() {
+			return fmt.Errorf("%s can't act", m.Name())
+		}
+		spell, ok := m.Spell(req.Spell)
+		if !ok {
+			return fmt.Errorf("%s doesn't know %q", m.Name(), req.Spell)
+		}
+		if err := castError(m, spell); err != nil {
+			return err
+		}
+		if err := w.StartAttack(m, spell, target); err != nil {
+			return err
+		}
+		b.acted = true
+	case "wait":
+		if req.Facing != "" {
+			dir, ok := parseDirection(req.Facing)
+			if !ok {
+				return fmt.Errorf("invalid facing: %q", req.Facing)
+			}
+			m.Face(dir)
+		}
+		m.FinishTurn(w, b.moved, b.acted)
+		w.pushBottom <- NextTurnState{}
+		b.moved = false
+		b.acted = false
+	}
+	return nil
+}
+
+// castError explains why m can't cast spell, like canCast does for players.
+func castError(m *Mob, spell Weapon) error {
+	switch {
+	case m.CanCast(spell):
+		return nil
+	case m.Silenced():
+		return fmt.Errorf("%s is silenced and can't cast spells", m.Name())
+	case m.Cooldown(spell) > 0:
+		return fmt.Errorf("%s is on cooldown for %d more turns", spell.Name, m.Cooldown(spell))
+	}
+	return fmt.Errorf("not enough MP to cast %s", spell.Name)
+}
+
+func (b *Bot) phase(w *World) string {
+	switch {
+	case w.current == nil:
+		return "title"
+	case w.gameOver:
+		return "lost"
+	case w.battleWon && w.level+1 >= len(mapsByLevel):
+		return "won"
+	case w.battleWon:
+		return "bonus"
+	}
+	return "battle"
+}
+
+// myTurn returns true if it's the bot's move, like GameWindow.myTurn.
+func (b *Bot) myTurn(w *World) bool {
+	// Busy lags behind until the next tick, but the bot runs on the world goroutine and can check for itself
+	busy := len(w.state) > 0 || len(w.push) > 0 || len(w.pushBottom) > 0
+	if busy || b.phase(w) != "battle" {
+		return false
+	}
+	m, ok := w.Up().(*Mob)
+	return ok && m.Team() == PlayerTeam && !m.AIControlled(w)
+}
+
+// ready returns true if the world is waiting on the bot.
+func (b *Bot) ready(w *World) bool {
+	return b.phase(w) != "battle" || b.myTurn(w)
+}
+
+func (b *Bot) pendingBonuses(w *World) []Bonus {
+	if b.bonuses == nil {
+		b.bonuses = generateBonuses(w.player, w.level)
+	}
+	return b.bonuses
+}
+
+func (b *Bot) paths(w *World, m *Mob) *PathMap {
+	loc := m.Loc()
+	return w.Map(loc.Map).Flood(loc, m.MoveRange(), m)
+}
+
+// log adds a combat log line for the next reply.
+func (b *Bot) addLog(line string) {
+	b.log = append(b.log, line)
+	if len(b.log) > botMaxLog {
+		b.log = b.log[len(b.log)-botMaxLog:]
+	}
+}
+
+func (b *Bot) state(w *World) *botState {
+	st := &botState{
+		Phase: b.phase(w),
+		Level: w.level,
+		Turn:  w.turn,
+		Score: w.score,
+		Log:   b.log,
+	}
+	b.log = nil
+	if st.Phase == "bonus" {
+		for _, bonus := range b.pendingBonuses(w) {
+			st.Bonuses = append(st.Bonuses, bonus.Name)
+		}
+	}
+	m := w.current
+	if m == nil {
+		return st
+	}
+	st.Map = botMapOf(w, m)
+	for _, obj := range m.Objects {
+		if mob, ok := obj.(*Mob); ok && w.Sees(PlayerTeam, mob) {
+			st.Units = append(st.Units, botUnitOf(mob))
+		}
+	}
+	sort.Slice(st.Units, func(i, j int) bool {
+		return st.Units[i].ID < st.Units[j].ID
+	})
+	if up := w.Up(); up != nil && w.Sees(PlayerTeam, up) {
+		st.Up = up.ID()
+	}
+	for _, entry := range w.Timeline(botOrderLength, b.moved, b.acted) {
+		if !w.Sees(PlayerTeam, entry.Turner) {
+			st.Order = append(st.Order, botTurn{Name: "unknown"})
+			continue
+		}
+		st.Order = append(st.Order, botTurn{ID: entry.Turner.ID(), Name: entry.Turner.Name()})
+	}
+
+	st.YourTurn = b.myTurn(w)
+	if !st.YourTurn {
+		return st
+	}
+	st.Moved, st.Acted = b.moved, b.acted
+	mob := w.Up().(*Mob)
+	if !b.moved && mob.CanMove() {
+		paths := b.paths(w, mob)
+		for y := 0; y < m.Height(); y++ {
+			for x := 0; x < m.Width(); x++ {
+				if paths.Cost(x, y) > 0 && w.CanSee(PlayerTeam, Loc{Map: m.Name, X: x, Y: y}) {
+					st.Moves = append(st.Moves, [2]int{x, y})
+				}
+			}
+		}
+	}
+	if !b.acted && mob.CanAct() {
+		st.Attacks = b.targets(w, mob, mob.Weapon())
+		for _, spell := range mob.Spells() {
+			if !mob.CanCast(spell) {
+				continue
+			}
+			if targets := b.targets(w, mob, spell); len(targets) > 0 {
+				if st.Casts == nil {
+					st.Casts = make(map[string][][2]int)
+				}
+				st.Casts[spell.Name] = targets
+			}
+		}
+	}
+	return st
+}
+
+// targets returns every tile that mob could use wep on, leaving out ones its team can't see.
+func (b *Bot) targets(w *World, mob *Mob, wep Weapon) [][2]int {
+	loc := mob.Loc()
+	m := w.Map(loc.Map)
+	// leave room for the extra range from shooting downhill
+	reach := wep.Range + 4
+	var targets [][2]int
+	for y := max(loc.Y-reach, 0); y <= min(loc.Y+reach, m.Height()-1); y++ {
+		for x := max(loc.X-reach, 0); x <= min(loc.X+reach, m.Width()-1); x++ {
+			if !wep.Magic && x == loc.X && y == loc.Y {
+				continue
+			}
+			at := Loc{Map: m.Name, X: x, Y: y}
+			if !w.CanSee(mob.Team(), at) {
+				continue
+			}
+			if _, err := w.aimAttack(mob, wep, at); err == nil {
+				targets = append(targets, [2]int{x, y})
+			}
+		}
+	}
+	return targets
+}
+
+func botMapOf(w *World, m *Map) *botMap {
+	bm := &botMap{
+		Name:   m.Name,
+		Width:  m.Width(),
+		Height: m.Height(),
+		Legend: make(map[string]botTerrain),
+	}
+	v := w.Vision(PlayerTeam)
+	heights := m.heightGrid()
+	for y := 0; y < m.Height(); y++ {
+		row := make([]rune, m.Width())
+		hrow := make([]int, m.Width())
+		for x := 0; x < m.Width(); x++ {
+			tile := m.TileAt(x, y)
+			if v != nil && !v.Seen(x, y) {
+				row[x] = ' '
+				hrow[x] = -1
+				continue
+			}
+			hrow[x] = heights[y*m.Width()+x]
+			r := tile.Ground.Rune
+			row[x] = r
+			if _, ok := bm.Legend[string(r)]; ok {
+				continue
+			}
+			cost := tile.MoveCost()
+			if !tile.Passable(false) {
+				cost = -1
+			}
+			bm.Legend[string(r)] = botTerrain{
+				Name:         tile.TerrainName(),
+				Cost:         cost,
+				FlyOnly:      tile.FlyOnly,
+				Defense:      tile.Defense,
+				Evasion:      tile.Evasion,
+				Cover:        tile.CoverValue(),
+				Hazard:       tile.Hazard,
+				Destructible: tile.Destructible(),
+				Use:          tile.Use,
+			}
+		}
+		bm.Tiles = append(bm.Tiles, string(row))
+		bm.Heights = append(bm.Heights, hrow)
+	}
+	return bm
+}
+
+func botUnitOf(m *Mob) botUnit {
+	loc := m.Loc()
+	u := botUnit{
+		ID:     m.ID(),
+		Name:   m.Name(),
+		Team:   m.Team(),
+		X:      loc.X,
+		Y:      loc.Y,
+		HP:     m.HP(),
+		MaxHP:  m.MaxHP(),
+		MP:     m.MP(),
+		MaxMP:  m.MaxMP(),
+		Shield: m.Shield().HP,
+		CT:     m.CT(),
+		Speed:  m.Speed(),
+		Move:   m.MoveRange(),
+		Jump:   m.Jump(),
+		Facing: m.Facing().String(),
+		Flying: m.Flying(),
+		Weapon: botWeaponOf(m, m.Weapon()),
+		Dead:   m.Dead(),
+	}
+	for _, spell := range m.Spells() {
+		u.Spells = append(u.Spells, botWeaponOf(m, spell))
+	}
+	for buff := range m.buffs {
+		u.Buffs = append(u.Buffs, buff.Name)
+	}
+	sort.Strings(u.Buffs)
+	return u
+}
+
+func botWeaponOf(m *Mob, wep Weapon) botWeapon {
+	bw := botWeapon{
+		Name:     wep.Name,
+		Range:    wep.Range,
+		Magic:    wep.Magic,
+		MP:       wep.MPCost,
+		Cooldown: m.Cooldown(wep),
+		Charge:   wep.Charge,
+	}
+	if wep.Damage.IsValid() {
+		bw.Damage = wep.Damage.Dice.String()
+		bw.Element = wep.Damage.Element.String()
+	}
+	return bw
+}
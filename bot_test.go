@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBot(t *testing.T) {
+	r := require.New(t)
+
+	w := newWorld()
+	go w.Run()
+	in, send := io.Pipe()
+	recv, out := io.Pipe()
+	go NewBot(w, in, out).Run()
+	replies := bufio.NewScanner(recv)
+	replies.Buffer(nil, 1<<20)
+	do := func(req string) botReply {
+		_, err := io.WriteString(send, req+"\n")
+		r.NoError(err)
+		r.True(replies.Scan())
+		var reply botReply
+		r.NoError(json.Unmarshal(replies.Bytes(), &reply))
+		return reply
+	}
+
+	reply := do(`{"id": 1, "cmd": "state"}`)
+	r.True(reply.OK)
+	r.Equal(`1`, string(reply.ID))
+	r.Equal("title", reply.State.Phase)
+
+	reply = do(`{"cmd": "dance"}`)
+	r.False(reply.OK)
+	r.Contains(reply.Error, "unknown command")
+
+	// replies once it's our turn
+	reply = do(`{"cmd": "start"}`)
+	r.True(reply.OK, reply.Error)
+	st := reply.State
+	r.Equal("battle", st.Phase)
+	r.True(st.YourTurn)
+	r.NotNil(st.Map)
+	r.Len(st.Map.Tiles, st.Map.Height)
+	r.NotEmpty(st.Moves)
+	r.NotEmpty(st.Units)
+
+	reply = do(`{"cmd": "attack", "x": -5, "y": -5}`)
+	r.False(reply.OK)
+	r.True(reply.State.YourTurn)
+	r.False(reply.State.Acted)
+
+	up := st.Up
+	move := st.Moves[0]
+	reply = do(`{"cmd": "move", "x": ` + strconv.Itoa(move[0]) + `, "y": ` + strconv.Itoa(move[1]) + `}`)
+	r.True(reply.OK, reply.Error)
+	r.True(reply.State.Moved)
+	r.Empty(reply.State.Moves)
+	for _, u := range reply.State.Units {
+		if u.ID == up {
+			r.Equal(move, [2]int{u.X, u.Y})
+		}
+	}
+
+	reply = do(`{"cmd": "move", "x": ` + strconv.Itoa(move[0]) + `, "y": ` + strconv.Itoa(move[1]) + `}`)
+	r.False(reply.OK)
+
+	reply = do(`{"cmd": "wait", "facing": "south"}`)
+	r.True(reply.OK, reply.Error)
+	r.NotEqual(st.Turn, reply.State.Turn)
+	r.False(reply.State.Moved)
+}
+
+func TestBotFog(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	w.fog = true
+	w.up = spawn(w, 41, 6, generateUnit("Knight"))
+	spawn(w, 42, 6, &Mob{team: 1})
+	spawn(w, 41, 2, &Mob{team: 1}) // behind the wall
+
+	b := NewBot(w, nil, nil)
+	st := b.state(w)
+	r.True(st.YourTurn)
+	r.NotEmpty(st.Moves)
+	for _, tile := range append(st.Moves, st.Attacks...) {
+		r.True(w.CanSee(PlayerTeam, Loc{Map: m.Name, X: tile[0], Y: tile[1]}), "%v is hidden", tile)
+	}
+	r.Contains(st.Attacks, [2]int{42, 6})
+	r.Equal(' ', []rune(st.Map.Tiles[2])[41])
+	r.Equal(-1, st.Map.Heights[2][41], "no peeking at unexplored terrain")
+
+	err := b.exec(w, botRequest{Cmd: "attack", X: 41, Y: 2})
+	r.Error(err)
+	r.Contains(err.Error(), "can't see")
+	r.False(b.acted)
+}
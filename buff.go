@@ -2,6 +2,7 @@ package main
 
 import (
 	"math/rand"
+	"strconv"
 )
 
 type Buff struct {
@@ -21,8 +22,10 @@ type Buff struct {
 	// Use it for temporarily modifying a unit's stats.
 	Affect func(w *World, m *Mob, stats *Stats)
 
-	BreakChance float64 // chance to break when unit starts turn: 0 = never, 0.1 = 10%
-	Life        int     // turns until this buff will guaranteed break: -1 = infinite
+	BreakChance   float64 // chance to break when unit starts turn: 0 = never, 0.1 = 10%
+	BreakOnDamage bool    // break when the unit takes damage
+	Life          int     // turns until this buff will guaranteed break: -1 = infinite
+	Harmful       bool    // removed by cleanse instead of dispel
 }
 
 type Uniqueness int
@@ -61,3 +64,11 @@ func (b *Buff) Broken() bool {
 func (b *Buff) Unique() bool {
 	return b.Uniqueness == Unique || b.Uniqueness == UniqueReplace
 }
+
+// Label is the buff's name with its remaining duration, like "poison 3".
+func (b *Buff) Label() string {
+	if b.Life < 0 {
+		return b.Name
+	}
+	return b.Name + " " + strconv.Itoa(b.Life)
+}
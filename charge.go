@@ -0,0 +1,125 @@
+package main
+
+// Charge is a spell that's being charged up.
+// It waits in line like a unit and goes off at its target tile when its CT is full,
+// so anyone standing there by then gets hit.
+type Charge struct {
+	id     ID
+	caster *Mob
+	spell  Weapon
+	target Loc
+	ct     int
+}
+
+// StartCharge has caster begin charging spell at target, paying for it up front.
+func (w *World) StartCharge(caster *Mob, spell Weapon, target Loc) {
+	caster.AddMP(-spell.MPCost)
+	caster.StartCooldown(spell)
+	// already paid
+	spell.MPCost = 0
+	spell.Cooldown = 0
+
+	target.Z = 0
+	charge := &Charge{
+		caster: caster,
+		spell:  spell,
+		target: target,
+	}
+	caster.charging = charge
+	w.Add(charge)
+	w.Broadcast(
+		caster.NameColored(),
+		" begins casting ",
+		GlyphsOf(spell.Name, StyleBold),
+		".",
+	)
+}
+
+// interrupt cancels this charge without casting it.
+func (c *Charge) interrupt(w *World) {
+	if c.caster.charging == c {
+		c.caster.charging = nil
+	}
+	w.Delete(c.id)
+	w.Broadcast(
+		c.caster.NameColored(),
+		"'s ",
+		c.spell.Name,
+		" was interrupted!",
+	)
+}
+
+func (c *Charge) Create(w *World) {
+	c.id = w.NextID()
+	w.Map(c.target.Map).Add(c)
+}
+
+func (c *Charge) ID() ID {
+	return c.id
+}
+
+func (c *Charge) Name() string {
+	return c.caster.Name() + "'s " + c.spell.Name
+}
+
+func (c *Charge) Glyph() Glyph {
+	g := GlyphOf('◌')
+	g.FG = c.caster.Glyph().FG
+	return g
+}
+
+func (c *Charge) Loc() Loc {
+	return c.target
+}
+
+func (c *Charge) Move(loc Loc) {
+	c.target = loc
+}
+
+func (c *Charge) Caster() *Mob {
+	return c.caster
+}
+
+func (c *Charge) Spell() Weapon {
+	return c.spell
+}
+
+func (c *Charge) CT() int {
+	return c.ct
+}
+
+func (c *Charge) Speed() int {
+	return c.spell.Charge
+}
+
+func (c *Charge) TurnTick(*World) {
+	c.ct += c.Speed()
+}
+
+// TakeTurn casts the spell.
+func (c *Charge) TakeTurn(w *World) {
+	if c.caster.charging == c {
+		c.caster.charging = nil
+	}
+	w.Delete(c.id)
+
+	m := w.Map(c.target.Map)
+	targets, hitlocs := findTargets(c.target, m, true, c.spell.HitboxSize, c.spell.Hitbox)
+	_, _, projpath := m.Raycast(c.caster.Loc(), c.target, true)
+	w.Broadcast(
+		c.caster.NameColored(),
+		" casts ",
+		GlyphsOf(c.spell.Name, StyleBold),
+		"!",
+	)
+	w.push <- &AttackState{
+		Char:     c.caster,
+		Targets:  targets,
+		Weapon:   c.spell,
+		ProjPath: projpath,
+		HitLocs:  hitlocs,
+	}
+	w.pushBottom <- NextTurnState{}
+}
+
+var _ Turner = (*Charge)(nil)
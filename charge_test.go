@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCharge(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := m.SpawnPoints[PlayerTeam][0]
+
+	caster := spawn(w, at.X, at.Y, &Mob{maxHP: 10, maxMP: 10})
+	spell := spellMeteor
+	target := Loc{Map: m.Name, X: at.X, Y: at.Y - 3}
+
+	w.StartCharge(caster, spell, target)
+	r.NotNil(caster.Charging())
+	r.Equal(10-spell.MPCost, caster.MP())
+	r.False(caster.CanCast(spell)) // on cooldown
+	r.Len(w.waitlist, 2)
+	r.IsType((*Charge)(nil), m.TileAtLoc(target).Top())
+
+	// getting hit breaks it
+	caster.Damage(w, Damage{Dice: dicey.MustParse("1")})
+	r.Nil(caster.Charging())
+	r.Len(w.waitlist, 1)
+	r.Nil(m.TileAtLoc(target).Top())
+
+	// cooldowns wear off after the given number of turns
+	for i := 0; i < spell.Cooldown; i++ {
+		caster.TakeTurn(w)
+		r.NotZero(caster.Cooldown(spell))
+	}
+	caster.TakeTurn(w)
+	r.Zero(caster.Cooldown(spell))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+)
+
+const (
+	baseHitChance  = 90
+	minHitChance   = 5
+	baseCritChance = 5   // percent
+	baseCritMult   = 150 // percent of normal damage
+)
+
+// Rolls tells you whether a weapon needs to roll to hit.
+// Magic, healing and other support spells always land.
+func (w Weapon) Rolls() bool {
+	return !w.Magic && w.Damage.Type != DamageHealing
+}
+
+func (w Weapon) CritChance() int {
+	if !w.Rolls() {
+		return 0
+	}
+	return baseCritChance + w.Crit
+}
+
+func (w Weapon) CritMultiplier() int {
+	if w.CritMult == 0 {
+		return baseCritMult
+	}
+	return w.CritMult
+}
+
+// HitBonus is the extra hit chance (percent) for attacking from this side.
+func (f Flank) HitBonus() int {
+	switch f {
+	case FlankSide:
+		return 10
+	case FlankBack:
+		return 25
+	}
+	return 0
+}
+
+// Accuracy is this mob's bonus to hit (percent).
+func (m *Mob) Accuracy() int {
+	return m.stats.Accuracy
+}
+
+// Evasion is this mob's chance to dodge (percent), including armor and terrain.
+func (m *Mob) Evasion(w *World) int {
+	return m.stats.Evasion + m.Armor().Evasion + m.Tile(w).Evasion
+}
+
+// HitChance returns the percent chance that source, standing at from, hits target with weapon.
+func HitChance(w *World, source *Mob, from Loc, target *Mob, weapon Weapon) int {
+	if !weapon.Rolls() || source == target {
+		return 100
+	}
+	chance := baseHitChance + weapon.Accuracy + source.Accuracy() - target.Evasion(w)
+	chance += flankOf(target.Loc(), target.Facing(), from).HitBonus()
+	chance -= Cover(w, source, from, target)
+	return clamp(chance, minHitChance, 100)
+}
+
+// attackFlank returns the side of target that an attack with weapon from the given tile hits.
+// Only physical attacks care about facing.
+func attackFlank(source *Mob, from Loc, target *Mob, weapon Weapon) Flank {
+	if target == source || weapon.Damage.Type != DamageNormal {
+		return FlankFront
+	}
+	return target.FlankFrom(from)
+}
+
+// rollHit decides whether an attack lands, and whether it's a critical hit.
+func rollHit(w *World, source, target *Mob, weapon Weapon) (hit, crit bool) {
+	if rand.Intn(100) >= HitChance(w, source, source.Loc(), target, weapon) {
+		return false, false
+	}
+	return true, rand.Intn(100) < weapon.CritChance()
+}
+
+// Reasons an attack can't be made.
+var (
+	errOutOfRange = errors.New("out of range")
+	errNoTarget   = errors.New("nothing to attack there")
+	errObstructed = errors.New("the attack is obstructed")
+)
+
+// aimAttack works out what happens when char uses wep at target,
+// returning an error if the attack isn't allowed.
+func (w *World) aimAttack(char *Mob, wep Weapon, target Loc) (*AttackState, error) {
+	loc := char.Loc()
+	m := w.Map(loc.Map)
+	if target.X < 0 || target.Y < 0 || target.X >= m.Width() || target.Y >= m.Height() {
+		return nil, errOutOfRange
+	}
+	as := &AttackState{Char: char, Weapon: wep}
+	if wep.Magic {
+		if !withinRange(loc, m, true, wep.Range, wep.Targeting, target.X, target.Y) {
+			return nil, errOutOfRange
+		}
+		t, hit := findTargets(target, m, true, wep.HitboxSize, wep.Hitbox)
+		if len(t) == 0 && wep.Charge == 0 && wep.Place == nil {
+			return nil, errNoTarget
+		}
+		as.Targets = t
+		as.HitLocs = hit
+		_, _, as.ProjPath = m.Raycast(loc, target, true)
+		return as, nil
+	}
+
+	hit, blocked, path := m.Raycast(loc, target, false)
+	if (hit == nil && !blocked) || (hit != nil && !hit.Attackable()) {
+		return nil, errNoTarget
+	}
+	if len(path) > wep.RangeFrom(m, loc, target) ||
+		(wep.Targeting == TargetingCross && ((loc.X != target.X) && (loc.Y != target.Y))) {
+		return nil, errOutOfRange
+	}
+	switch {
+	case blocked && path[len(path)-1] == target && m.TileAtLoc(target).Destructible():
+		// breaking down a door, crate, etc.
+		as.HitLocs = []Loc{target}
+	case blocked:
+		return nil, errObstructed
+	default:
+		as.Targets = []*Mob{hit}
+	}
+	as.ProjPath = path
+	return as, nil
+}
+
+// StartAttack has char use wep at target, or start charging it for spells with a charge time.
+// It returns an error instead if the attack isn't allowed.
+func (w *World) StartAttack(char *Mob, wep Weapon, target Loc) error {
+	as, err := w.aimAttack(char, wep, target)
+	if err != nil {
+		return err
+	}
+	if wep.Charge > 0 {
+		w.StartCharge(char, wep, target)
+		return nil
+	}
+	w.push <- as
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+)
+
+const (
+	wallCover = 25 // default cover (percent) from walls and other obstacles
+	unitCover = 15 // cover from units in the way
+
+	// coverWidth is how close (in tiles) something has to be to the line of fire to get in the way.
+	coverWidth = 0.75
+)
+
+// CoverValue is how much cover (percent) this tile gives to units being shot past it.
+func (t *Tile) CoverValue() int {
+	switch {
+	case t.Cover < 0:
+		return 0
+	case t.Cover > 0:
+		return t.Cover
+	case t.Collides:
+		return wallCover
+	}
+	return 0
+}
+
+// Cover returns how much a shot from source at from to target is penalized (percent)
+// by walls and units close to the line of fire, or terrain it passes through.
+// Only the best cover counts. Adjacent attacks never have cover.
+func Cover(w *World, source *Mob, from Loc, target *Mob) int {
+	to := target.Loc()
+	if abs(to.X-from.X)+abs(to.Y-from.Y) <= 1 {
+		return 0
+	}
+	m := w.Map(to.Map)
+	dx, dy := float64(to.X-from.X), float64(to.Y-from.Y)
+	length := math.Hypot(dx, dy)
+
+	_, _, path := m.Raycast(from, to, true)
+	onPath := make(map[[2]int]bool, len(path))
+	for _, loc := range path {
+		onPath[[2]int{loc.X, loc.Y}] = true
+	}
+
+	best := 0
+	for y := min(from.Y, to.Y) - 1; y <= max(from.Y, to.Y)+1; y++ {
+		for x := min(from.X, to.X) - 1; x <= max(from.X, to.X)+1; x++ {
+			if (x == from.X && y == from.Y) || (x == to.X && y == to.Y) {
+				continue
+			}
+			if x < 0 || y < 0 || x >= m.Width() || y >= m.Height() {
+				continue
+			}
+			// how far along the line, and how far off to the side
+			px, py := float64(x-from.X), float64(y-from.Y)
+			along := (px*dx + py*dy) / (length * length)
+			if along <= 0 || along >= 1 {
+				continue
+			}
+			off := math.Abs(px*dy-py*dx) / length
+			tile := m.TileAt(x, y)
+			cover := 0
+			switch {
+			case onPath[[2]int{x, y}]:
+				// shooting through brush and the like, walls would have blocked it outright
+				if !tile.Collides {
+					cover = tile.CoverValue()
+				}
+			case off <= coverWidth:
+				cover = tile.CoverValue()
+				for _, obj := range tile.Objects {
+					if mob, ok := obj.(*Mob); ok && mob != source && mob != target && !mob.Dead() {
+						cover = max(cover, unitCover)
+					}
+				}
+			}
+			best = max(best, cover)
+		}
+	}
+	return best
+}
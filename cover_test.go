@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCover(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := func(x, y int) Loc {
+		return Loc{Map: m.Name, X: x, Y: y}
+	}
+
+	archer := spawn(w, 35, 13, &Mob{maxHP: 50, team: PlayerTeam})
+	target := spawn(w, 45, 13, &Mob{maxHP: 50, team: 1})
+
+	// out in the open, or right next to each other
+	r.Equal(0, Cover(w, archer, archer.Loc(), target))
+	r.Equal(0, Cover(w, archer, at(44, 13), target))
+
+	// shooting past someone
+	m.Move(target, 45, 14)
+	bystander := spawn(w, 41, 13, &Mob{maxHP: 50, team: 1})
+	hit, _, _ := m.Raycast(archer.Loc(), target.Loc(), false)
+	r.Equal(target, hit)
+	r.Equal(unitCover, Cover(w, archer, archer.Loc(), target))
+	m.Move(bystander, 41, 10)
+	r.Equal(0, Cover(w, archer, archer.Loc(), target))
+
+	// hiding behind the corner of a pillar
+	m.Move(target, 44, 5)
+	r.Equal(wallCover, Cover(w, archer, at(35, 4), target))
+	// but stepping out gives a clear shot
+	r.Equal(0, Cover(w, archer, at(35, 5), target))
+
+	// shows up in the hit chance and forecast
+	m.Move(archer, 35, 4)
+	wep := Weapon{Range: 10, Damage: weaponBow.Damage}
+	r.Equal(wallCover, forecast(w, archer, wep, target).Cover)
+	r.Equal(HitChance(w, archer, at(35, 5), target, wep)-wallCover, HitChance(w, archer, archer.Loc(), target, wep))
+}
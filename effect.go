@@ -46,3 +46,10 @@ func (e *Effect) Tick(w *World, tick int64) {
 		w.Delete(e.ID())
 	}
 }
+
+func (e *Effect) NextTick(_ *World, now int64) int64 {
+	if e.life == -1 {
+		return -1
+	}
+	return now + 1
+}
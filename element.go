@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Element is the kind of damage an attack deals.
+type Element int
+
+const (
+	ElementNone Element = iota
+	ElementSlash
+	ElementPierce
+	ElementBlunt
+	ElementFire
+	ElementIce
+	ElementLightning
+	ElementHoly
+	ElementPoison
+)
+
+func (e Element) String() string {
+	return [...]string{"", "slash", "pierce", "blunt", "fire", "ice", "lightning", "holy", "poison"}[e]
+}
+
+// Physical returns true for slashing, piercing and blunt damage.
+func (e Element) Physical() bool {
+	return e == ElementSlash || e == ElementPierce || e == ElementBlunt
+}
+
+func (e Element) Color() Color {
+	switch e {
+	case ElementFire:
+		return ColorBrightRed
+	case ElementIce:
+		return Color256(117)
+	case ElementLightning:
+		return ColorBrightYellow
+	case ElementHoly:
+		return Color256(229)
+	case ElementPoison:
+		return ColorDiarrhea
+	}
+	return Color256(250)
+}
+
+// Affinity is how a unit reacts to an element.
+type Affinity int
+
+const (
+	AffinityNormal Affinity = iota
+	AffinityResist          // half damage
+	AffinityWeak            // 150% damage
+	AffinityImmune          // no damage
+	AffinityAbsorb          // heals instead
+)
+
+var affinityNames = map[Affinity]string{
+	AffinityResist: "resist",
+	AffinityWeak:   "weak",
+	AffinityImmune: "immune",
+	AffinityAbsorb: "absorb",
+}
+
+func (a Affinity) String() string {
+	return affinityNames[a]
+}
+
+// Scale returns the percent of damage taken.
+func (a Affinity) Scale() int {
+	switch a {
+	case AffinityResist:
+		return 50
+	case AffinityWeak:
+		return 150
+	case AffinityImmune:
+		return 0
+	case AffinityAbsorb:
+		return -100
+	}
+	return 100
+}
+
+// Affinities maps elements to how a unit reacts to them. Missing elements are normal.
+type Affinities map[Element]Affinity
+
+// Of returns the affinity to e.
+func (a Affinities) Of(e Element) Affinity {
+	if e == ElementNone {
+		return AffinityNormal
+	}
+	return a[e]
+}
+
+// With returns the elements with the given affinity, in order.
+func (a Affinities) With(aff Affinity) []Element {
+	var elems []Element
+	for e, other := range a {
+		if other == aff {
+			elems = append(elems, e)
+		}
+	}
+	sort.Slice(elems, func(i, j int) bool {
+		return elems[i] < elems[j]
+	})
+	return elems
+}
+
+// Glyphs describes these affinities, like "weak: fire; resist: ice".
+func (a Affinities) Glyphs() []Glyph {
+	var text []Glyph
+	for _, aff := range []Affinity{AffinityWeak, AffinityResist, AffinityImmune, AffinityAbsorb} {
+		elems := a.With(aff)
+		if len(elems) == 0 {
+			continue
+		}
+		if len(text) > 0 {
+			text = Concat(text, "; ")
+		}
+		text = Concat(text, aff.String(), ": ")
+		for i, e := range elems {
+			if i > 0 {
+				text = Concat(text, ", ")
+			}
+			text = Concat(text, GlyphsOf(e.String(), StyleFG(e.Color())))
+		}
+	}
+	return text
+}
+
+// Names lists the elements with the given affinity, for plain text.
+func (a Affinities) Names(aff Affinity) string {
+	var names []string
+	for _, e := range a.With(aff) {
+		names = append(names, e.String())
+	}
+	return strings.Join(names, ", ")
+}
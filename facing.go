@@ -0,0 +1,106 @@
+package main
+
+import "strings"
+
+// Direction is the way a unit is facing.
+type Direction int
+
+const (
+	North Direction = iota
+	East
+	South
+	West
+)
+
+func (d Direction) Delta() (dx, dy int) {
+	switch d {
+	case North:
+		return 0, -1
+	case East:
+		return 1, 0
+	case South:
+		return 0, 1
+	case West:
+		return -1, 0
+	}
+	return 0, 0
+}
+
+func (d Direction) Arrow() rune {
+	return []rune("↑→↓←")[d%4]
+}
+
+func (d Direction) String() string {
+	return [...]string{"north", "east", "south", "west"}[d%4]
+}
+
+// parseDirection reads a direction like "north" or "n".
+func parseDirection(s string) (Direction, bool) {
+	for dir := North; dir <= West; dir++ {
+		if strings.EqualFold(s, dir.String()) || strings.EqualFold(s, dir.String()[:1]) {
+			return dir, true
+		}
+	}
+	return 0, false
+}
+
+// directionTo returns the direction that best points from one tile to another.
+// Ties between axes go to the horizontal one.
+func directionTo(from, to Loc) Direction {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if abs(dx) >= abs(dy) && dx != 0 {
+		if dx > 0 {
+			return East
+		}
+		return West
+	}
+	if dy < 0 {
+		return North
+	}
+	return South
+}
+
+// Flank is the side of a unit that an attack comes from.
+type Flank int
+
+const (
+	FlankFront Flank = iota
+	FlankSide
+	FlankBack
+)
+
+// flankOf returns which side of a unit at loc, facing the given way, is exposed to an attack from src.
+func flankOf(loc Loc, facing Direction, src Loc) Flank {
+	fx, fy := facing.Delta()
+	dx, dy := src.X-loc.X, src.Y-loc.Y
+	dot := fx*dx + fy*dy
+	cross := abs(fx*dy - fy*dx)
+	switch {
+	case dot > cross:
+		return FlankFront
+	case -dot > cross:
+		return FlankBack
+	}
+	return FlankSide
+}
+
+// DamageScale is the percent of normal damage that attacks from this side deal.
+func (f Flank) DamageScale() int {
+	switch f {
+	case FlankSide:
+		return 125
+	case FlankBack:
+		return 150
+	}
+	return 100
+}
+
+func (f Flank) String() string {
+	switch f {
+	case FlankSide:
+		return "from the side"
+	case FlankBack:
+		return "from behind"
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlank(t *testing.T) {
+	r := require.New(t)
+
+	at := Loc{X: 5, Y: 5}
+	r.Equal(FlankFront, flankOf(at, North, Loc{X: 5, Y: 4}))
+	r.Equal(FlankBack, flankOf(at, North, Loc{X: 5, Y: 6}))
+	r.Equal(FlankSide, flankOf(at, North, Loc{X: 4, Y: 5}))
+	r.Equal(FlankSide, flankOf(at, North, Loc{X: 6, Y: 5}))
+	r.Equal(FlankBack, flankOf(at, East, Loc{X: 3, Y: 5}))
+
+	// diagonals count as the side
+	r.Equal(FlankSide, flankOf(at, South, Loc{X: 6, Y: 6}))
+	// unless they're mostly behind
+	r.Equal(FlankBack, flankOf(at, South, Loc{X: 6, Y: 2}))
+}
+
+func TestDirectionTo(t *testing.T) {
+	r := require.New(t)
+
+	at := Loc{X: 5, Y: 5}
+	r.Equal(North, directionTo(at, Loc{X: 5, Y: 1}))
+	r.Equal(South, directionTo(at, Loc{X: 4, Y: 9}))
+	r.Equal(East, directionTo(at, Loc{X: 8, Y: 4}))
+	r.Equal(West, directionTo(at, Loc{X: 2, Y: 8}))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/guregu/dicey"
+)
+
+// Feature makes a tile destructible or interactive, like a door, crate, lever or chest.
+type Feature struct {
+	HP      int    // tiles with HP can be broken by attacks
+	Becomes string // glyph this tile turns into when it's broken or used, default "."
+	Use     string // verb for using it, like "open"; units standing next to it can use it
+	Toggles string // using it also flips every tile in this group, like a lever
+	Group   string // tiles flipped by levers
+	Loot    bool   // using it hands out treasure
+}
+
+// merge overlays the set fields of other on top of f, like Terrain.merge.
+func (f *Feature) merge(other Feature) {
+	if other.HP != 0 {
+		f.HP = other.HP
+	}
+	if other.Becomes != "" {
+		f.Becomes = other.Becomes
+	}
+	if other.Use != "" {
+		f.Use = other.Use
+	}
+	if other.Toggles != "" {
+		f.Toggles = other.Toggles
+	}
+	if other.Group != "" {
+		f.Group = other.Group
+	}
+	if other.Loot {
+		f.Loot = true
+	}
+}
+
+// Destructible returns true if this tile can be broken by attacks.
+func (f Feature) Destructible() bool {
+	return f.HP > 0
+}
+
+// Usable returns true if units can use this tile.
+func (f Feature) Usable() bool {
+	return f.Use != ""
+}
+
+func (f Feature) becomes() rune {
+	if f.Becomes == "" {
+		return '.'
+	}
+	return []rune(f.Becomes)[0]
+}
+
+// SetTile turns the tile at x, y into the given glyph.
+// The original is remembered so Reset can put it back.
+func (m *Map) SetTile(x, y int, r rune) {
+	tile := m.TileAt(x, y)
+	if !tile.IsValid() {
+		return
+	}
+	if m.changed == nil {
+		m.changed = make(map[*Tile]Tile)
+	}
+	if _, ok := m.changed[tile]; !ok {
+		m.changed[tile] = *tile
+	}
+	m.build(tile, r)
+	m.invalidatePaths()
+}
+
+// restore puts back every tile changed since the map was loaded,
+// and patches up the ones that were only damaged.
+func (m *Map) restore() {
+	for tile, orig := range m.changed {
+		tile.Ground = orig.Ground
+		tile.Collides = orig.Collides
+		tile.Terrain = orig.Terrain
+		tile.Feature = orig.Feature
+	}
+	for y := range m.Tiles {
+		for _, tile := range m.Tiles[y] {
+			tile.hp = tile.Feature.HP
+		}
+	}
+	if len(m.changed) > 0 {
+		m.changed = nil
+		m.invalidatePaths()
+	}
+}
+
+// HP returns how much more damage a destructible tile can take.
+func (t *Tile) HP() int {
+	return t.hp
+}
+
+// CanUse returns true if m is standing next to this tile and can use it.
+func (t *Tile) CanUse(m *Mob) bool {
+	if !t.Usable() || !t.IsValid() {
+		return false
+	}
+	loc := m.Loc()
+	if loc.Map != t.Map.Name || abs(loc.X-t.X)+abs(loc.Y-t.Y) != 1 {
+		return false
+	}
+	// don't shut doors on people
+	for _, obj := range t.Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() {
+			return false
+		}
+	}
+	return true
+}
+
+// UsableNear returns the tiles m could use from where it's standing.
+func (w *World) UsableNear(m *Mob) []*Tile {
+	loc := m.Loc()
+	gm := w.Map(loc.Map)
+	var tiles []*Tile
+	for _, dir := range neighbors {
+		x, y := loc.X+dir[0], loc.Y+dir[1]
+		if x < 0 || y < 0 {
+			continue
+		}
+		if tile := gm.TileAt(x, y); tile.CanUse(m) {
+			tiles = append(tiles, tile)
+		}
+	}
+	return tiles
+}
+
+// UseTile has m use a tile: open a door, pull a lever, open a chest, etc.
+func (w *World) UseTile(m *Mob, tile *Tile) {
+	name, toggles, loot := tile.TerrainName(), tile.Toggles, tile.Loot
+	w.Broadcast(m.NameColored(), " ", pastTense(tile.Use), " the ", name, ".")
+	w.changeTile(tile)
+	if toggles != "" {
+		w.toggleGroup(tile.Map, toggles)
+	}
+	if loot {
+		w.loot(m)
+	}
+}
+
+// UseState has a unit use a tile, see UseTile.
+type UseState struct {
+	Char *Mob
+	Tile *Tile
+}
+
+func (us *UseState) Run(w *World) bool {
+	w.UseTile(us.Char, us.Tile)
+	return true
+}
+
+// DamageTile hits a destructible tile with weapon, breaking it if it runs out of HP.
+func (w *World) DamageTile(loc Loc, source *Mob, weapon Weapon) {
+	tile := w.Map(loc.Map).TileAtLoc(loc)
+	dmg := weapon.Damage
+	if !tile.Destructible() || !dmg.IsValid() || dmg.Type == DamageNone || dmg.Type == DamageHealing {
+		return
+	}
+	hit := dmg.Roll()
+	tile.hp -= hit
+	name := tile.TerrainName()
+	if tile.hp > 0 {
+		w.Broadcast(source.NameColored(), " hit the ", name, " for ", ColorDamage(hit), " damage.")
+		return
+	}
+	w.Broadcast(source.NameColored(), " destroyed the ", name, "!")
+	w.changeTile(tile)
+}
+
+// changeTile turns a tile into whatever it becomes.
+func (w *World) changeTile(tile *Tile) {
+	tile.Map.SetTile(tile.X, tile.Y, tile.becomes())
+	// the floor might have gone out from under someone
+	for _, obj := range tile.Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() {
+			w.enterHazard(mob)
+		}
+	}
+}
+
+// toggleGroup flips every tile in group, like a lever raising a bridge.
+func (w *World) toggleGroup(m *Map, group string) {
+	var tiles []*Tile
+	for y := range m.Tiles {
+		for _, tile := range m.Tiles[y] {
+			if tile.Group == group {
+				tiles = append(tiles, tile)
+			}
+		}
+	}
+	for _, tile := range tiles {
+		w.changeTile(tile)
+	}
+}
+
+// loot hands out a random treasure to m.
+func (w *World) loot(m *Mob) {
+	switch n := rand.Intn(3); {
+	case n == 0 && m.HP() < m.MaxHP():
+		healed := m.Damage(w, Damage{Dice: dicey.MustParse("2d6+4"), Type: DamageHealing})
+		w.Broadcast(m.NameColored(), " found a potion and recovered ", ColorDamage(healed), " HP!")
+	case n == 1 && m.MP() < m.MaxMP():
+		m.AddMP(10)
+		w.Broadcast(m.NameColored(), " found an ether and recovered ", GlyphsOf("10", StyleFG(ColorBrightBlue)), " MP!")
+	default:
+		gold := 100 + rand.Intn(5)*50
+		w.score += gold
+		w.Broadcast(m.NameColored(), " found ", GlyphsOf(fmt.Sprintf("%d gold", gold), StyleFG(ColorBrightYellow)), "!")
+	}
+}
+
+// pastTense turns a verb like "open" into "opened".
+func pastTense(verb string) string {
+	if strings.HasSuffix(verb, "e") {
+		return verb + "d"
+	}
+	return verb + "ed"
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "chambers")
+	mob := spawn(w, 51, 7, &Mob{maxHP: 50, team: PlayerTeam})
+
+	// opening and closing doors
+	door := m.TileAt(52, 7)
+	r.True(door.Collides)
+	r.Equal([]*Tile{door}, w.UsableNear(mob))
+	w.UseTile(mob, door)
+	r.False(door.Collides)
+	r.Equal('\'', door.Ground.Rune)
+	r.Equal("close", door.Use)
+
+	// breaking crates
+	crate := m.TileAt(42, 4)
+	r.True(crate.Destructible())
+	axe := Weapon{Damage: Damage{Dice: dicey.MustParse("20")}}
+	w.DamageTile(Loc{Map: m.Name, X: 42, Y: 4}, mob, axe)
+	r.False(crate.Collides)
+	r.Equal("rubble", crate.Name)
+
+	// everything's back to normal next battle
+	m.Reset()
+	r.True(door.Collides)
+	r.Equal("open", door.Use)
+	r.True(crate.Collides)
+	r.Equal(crate.Feature.HP, crate.HP())
+}
+
+func TestLever(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "islandfort")
+	mob := spawn(w, 54, 5, &Mob{maxHP: 50, team: PlayerTeam})
+
+	bridge := m.TileAt(44, 5)
+	r.True(bridge.Passable(false))
+	w.UseTile(mob, m.TileAt(53, 5))
+	r.False(bridge.Passable(false))
+	r.True(bridge.Passable(true))
+	w.UseTile(mob, m.TileAt(53, 5))
+	r.True(bridge.Passable(false))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/guregu/dicey"
+)
+
+// ForcedMoveState shoves a unit up to Power tiles in a straight line, one step at a time.
+// Like a raycast, it stops at the first wall or unit in the way,
+// and the unit takes damage for each tile it didn't get to travel.
+type ForcedMoveState struct {
+	Mob    *Mob
+	Source *Mob
+	Dir    [2]int
+	Power  int
+
+	moved int
+	wait  int
+}
+
+const forcedMoveSpeed = 1
+
+// Knockback pushes target n tiles away from source.
+func (w *World) Knockback(target, source *Mob, n int) {
+	w.forceMove(target, source, directionFrom(source.Loc(), target.Loc()), n)
+}
+
+// Pull drags target up to n tiles toward source.
+func (w *World) Pull(target, source *Mob, n int) {
+	w.forceMove(target, source, directionFrom(target.Loc(), source.Loc()), n)
+}
+
+func (w *World) forceMove(target, source *Mob, dir [2]int, n int) {
+	if target.Dead() || n <= 0 || dir == [2]int{} {
+		return
+	}
+	w.push <- &ForcedMoveState{
+		Mob:    target,
+		Source: source,
+		Dir:    dir,
+		Power:  n,
+	}
+}
+
+// directionFrom returns the straight direction that leads away from `from` through `to`,
+// going along whichever axis is further apart.
+func directionFrom(from, to Loc) [2]int {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	switch {
+	case dx == 0 && dy == 0:
+		return [2]int{}
+	case abs(dx) >= abs(dy):
+		return [2]int{sign(dx), 0}
+	default:
+		return [2]int{0, sign(dy)}
+	}
+}
+
+func (fm *ForcedMoveState) Run(w *World) bool {
+	if fm.wait < forcedMoveSpeed {
+		fm.wait++
+		return false
+	}
+	fm.wait = 0
+	return fm.step(w)
+}
+
+// step moves one tile and returns true when the unit has come to a stop.
+func (fm *ForcedMoveState) step(w *World) bool {
+	if fm.Mob.Dead() {
+		return true
+	}
+	if fm.moved >= fm.Power {
+		w.enterHazard(fm.Mob)
+		return true
+	}
+
+	loc := fm.Mob.Loc()
+	m := w.Map(loc.Map)
+	x, y := loc.X+fm.Dir[0], loc.Y+fm.Dir[1]
+	left := fm.Power - fm.moved
+	if x < 0 || y < 0 {
+		fm.slam(w, "the wall", left)
+		return true
+	}
+	next := m.TileAt(x, y)
+	for _, obj := range next.Objects {
+		other, ok := obj.(*Mob)
+		if !ok || other.Dead() {
+			continue
+		}
+		if other == fm.Source {
+			// pulled right up to them
+			w.enterHazard(fm.Mob)
+			return true
+		}
+		fm.crash(w, other, left)
+		return true
+	}
+	here := m.TileAtLoc(loc)
+	switch {
+	case next.Collides || !next.IsValid():
+		fm.slam(w, "the "+next.TerrainName(), left)
+		return true
+	case !fm.Mob.Flying() && next.Height-here.Height > fm.Mob.Jump():
+		fm.slam(w, "a ledge", left)
+		return true
+	case next.HasCollider(fm.Mob):
+		fm.slam(w, "something", left)
+		return true
+	}
+
+	m.Move(fm.Mob, x, y)
+	fm.moved++
+	if w.collide(fm.Mob) {
+		w.enterHazard(fm.Mob)
+		return true
+	}
+	return false
+}
+
+// collisionDamage is how much it hurts to be stopped short with left tiles to go.
+func collisionDamage(left int) Damage {
+	return Damage{Dice: dicey.MustParse(fmt.Sprintf("%dd4", left)), Element: ElementBlunt}
+}
+
+// slam hits the unit against a wall or other obstacle.
+func (fm *ForcedMoveState) slam(w *World, what string, left int) {
+	dmg := fm.Mob.Damage(w, collisionDamage(left))
+	w.Broadcast(
+		fm.Mob.NameColored(),
+		" slammed into ",
+		what,
+		" for ",
+		ColorDamage(dmg),
+		" damage!",
+	)
+	w.checkDied(fm.Mob)
+	w.enterHazard(fm.Mob)
+}
+
+// crash knocks the unit into another one, hurting them both.
+func (fm *ForcedMoveState) crash(w *World, other *Mob, left int) {
+	dmg := fm.Mob.Damage(w, collisionDamage(left))
+	otherDmg := other.Damage(w, collisionDamage(left))
+	w.Broadcast(
+		fm.Mob.NameColored(),
+		" crashed into ",
+		other.NameColored(),
+		"! (",
+		ColorDamage(dmg),
+		" and ",
+		ColorDamage(otherDmg),
+		" damage)",
+	)
+	w.checkDied(fm.Mob)
+	w.checkDied(other)
+	w.enterHazard(fm.Mob)
+}
+
+// enterHazard hurts walkers that end up on a hazardous tile.
+func (w *World) enterHazard(m *Mob) {
+	tile := m.Tile(w)
+	if tile.Hazard <= 0 || m.Flying() || m.Dead() {
+		return
+	}
+	dmg := m.Damage(w, Damage{Dice: dicey.MustParse(strconv.Itoa(tile.Hazard))})
+	w.Broadcast(
+		m.NameColored(),
+		" fell into the ",
+		tile.TerrainName(),
+		" for ",
+		ColorDamage(dmg),
+		" damage!",
+	)
+	w.checkDied(m)
+}
+
+// checkDied announces m's death if it just died.
+func (w *World) checkDied(m *Mob) {
+	if m.Dead() {
+		w.Broadcast(m.NameColored(), " died.")
+	}
+}
+
+// knockbackOnHit returns an OnHit func that pushes the target n tiles away.
+func knockbackOnHit(n int) func(w *World, source *Mob, target *Mob) {
+	return func(w *World, source *Mob, target *Mob) {
+		w.Knockback(target, source, n)
+	}
+}
+
+// pullOnHit returns an OnHit func that drags the target up to n tiles closer.
+func pullOnHit(n int) func(w *World, source *Mob, target *Mob) {
+	return func(w *World, source *Mob, target *Mob) {
+		w.Pull(target, source, n)
+	}
+}
+
+var spellShieldBash = Weapon{
+	Name:     "shield bash",
+	Damage:   Damage{Dice: dicey.MustParse("1d6"), Element: ElementBlunt},
+	Range:    1,
+	Cooldown: 2,
+	Buffs:    []string{"knockback 2"},
+	OnHit:    knockbackOnHit(2),
+}
+
+var spellGust = Weapon{
+	Name: "gust",
+	Damage: Damage{
+		Dice: dicey.MustParse("1d4"),
+		Type: DamageMagic,
+	},
+	Range:      5,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '≋', SGR: SGR{FG: Color256(152)}},
+	MPCost:     6,
+	Buffs:      []string{"knockback 2"},
+	OnHit:      knockbackOnHit(2),
+}
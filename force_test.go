@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForcedMove(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	run := func(fm *ForcedMoveState) {
+		for i := 0; i < 10 && !fm.step(w); i++ {
+		}
+	}
+
+	wall := m.TileAt(40, 15)
+	r.True(wall.Collides)
+
+	// knocked into the wall with 2 tiles to spare
+	source := spawn(w, 40, 12, &Mob{maxHP: 50})
+	target := spawn(w, 40, 13, &Mob{maxHP: 50})
+	r.Equal([2]int{0, 1}, directionFrom(source.Loc(), target.Loc()))
+	run(&ForcedMoveState{Mob: target, Source: source, Dir: [2]int{0, 1}, Power: 3})
+	r.Equal(14, target.Loc().Y)
+	r.Less(target.HP(), 50)
+	r.GreaterOrEqual(target.HP(), 50-8)
+
+	// pulled back until it bumps into the puller, no damage
+	hp := target.HP()
+	run(&ForcedMoveState{Mob: target, Source: source, Dir: directionFrom(target.Loc(), source.Loc()), Power: 5})
+	r.Equal(13, target.Loc().Y)
+	r.Equal(hp, target.HP())
+
+	// knocked into someone else
+	run(&ForcedMoveState{Mob: source, Dir: [2]int{0, 1}, Power: 1})
+	r.Equal(12, source.Loc().Y)
+	r.Less(source.HP(), 50)
+	r.Less(target.HP(), hp)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/guregu/dicey"
+)
+
+// Forecast is the predicted outcome of an attack or spell on a single unit.
+type Forecast struct {
+	Target *Mob
+	Hit    int // percent chance to land
+	Cover  int // how much the hit chance is lowered by cover
+	Crit   int // percent chance of a critical hit
+
+	// Min and Max come from the lowest and highest rolls, Avg is the average of a hit.
+	// Damage is after defense; negative numbers are healing.
+	Min, Avg, Max int
+
+	HP    int      // target's HP after an average hit
+	Kill  int      // percent chance to kill
+	Buffs []string // buffs that will be applied on a hit
+}
+
+// Heals returns true if this attack heals instead of dealing damage.
+func (fc Forecast) Heals() bool {
+	return fc.Max < 0
+}
+
+// ForecastAttack predicts what happens when source uses weapon on each of targets.
+func ForecastAttack(w *World, source *Mob, weapon Weapon, targets []*Mob) []Forecast {
+	forecasts := make([]Forecast, 0, len(targets))
+	for _, target := range targets {
+		if target.Dead() {
+			continue
+		}
+		forecasts = append(forecasts, forecast(w, source, weapon, target))
+	}
+	return forecasts
+}
+
+// coverOf is the cover target has from source's attack with weapon from the given tile.
+func coverOf(w *World, source *Mob, from Loc, weapon Weapon, target *Mob) int {
+	if !weapon.Rolls() || source == target {
+		return 0
+	}
+	return Cover(w, source, from, target)
+}
+
+func forecast(w *World, source *Mob, weapon Weapon, target *Mob) Forecast {
+	return forecastFrom(w, source, source.Loc(), weapon, target)
+}
+
+// forecastFrom predicts the outcome as if source were attacking from the given tile.
+func forecastFrom(w *World, source *Mob, from Loc, weapon Weapon, target *Mob) Forecast {
+	fc := Forecast{
+		Target: target,
+		Hit:    HitChance(w, source, from, target, weapon),
+		Cover:  coverOf(w, source, from, weapon, target),
+		Crit:   weapon.CritChance(),
+		HP:     target.HP(),
+		Buffs:  weapon.Buffs,
+	}
+	if !weapon.Damage.IsValid() || weapon.Damage.Type == DamageNone {
+		return fc
+	}
+
+	normal := weapon.Damage
+	normal.Scale = attackFlank(source, from, target, weapon).DamageScale()
+	crit := normal
+	crit.Scale = crit.Scale * weapon.CritMultiplier() / 100
+	critOdds := float64(fc.Crit) / 100
+
+	odds := diceOdds(weapon.Damage.Dice)
+	rolls := make([]int, 0, len(odds))
+	for roll := range odds {
+		rolls = append(rolls, roll)
+	}
+	sort.Ints(rolls)
+
+	// shields soak up damage before HP
+	hp := fc.HP
+	if shield := target.Shield(); shield.Blocks(weapon.Damage) {
+		hp += shield.HP
+	}
+
+	var avg, kill float64
+	for _, roll := range rolls {
+		p := odds[roll]
+		dmg := target.mitigate(w, normal, normal.scaled(roll))
+		critDmg := target.mitigate(w, crit, crit.scaled(roll))
+		avg += p * ((1-critOdds)*float64(dmg) + critOdds*float64(critDmg))
+		if dmg >= hp {
+			kill += p * (1 - critOdds)
+		}
+		if critDmg >= hp {
+			kill += p * critOdds
+		}
+	}
+	low, high := rolls[0], rolls[len(rolls)-1]
+	fc.Min = target.mitigate(w, normal, normal.scaled(low))
+	fc.Max = target.mitigate(w, normal, normal.scaled(high))
+	if fc.Crit > 0 {
+		fc.Max = target.mitigate(w, crit, crit.scaled(high))
+	}
+	fc.Avg = int(avg + 0.5)
+	if avg < 0 {
+		fc.Avg = int(avg - 0.5)
+	}
+	fc.HP = clamp(fc.HP-fc.Avg+target.Shield().Absorb(weapon.Damage, fc.Avg), 0, target.MaxHP())
+	fc.Kill = int(kill * float64(fc.Hit))
+	return fc
+}
+
+// diceOdds returns the chance of rolling every possible total with d.
+func diceOdds(d dicey.Dice) map[int]float64 {
+	odds := map[int]float64{0: 1}
+	formula := strings.NewReplacer(" ", "", "-", "+-").Replace(d.String())
+	for _, term := range strings.Split(formula, "+") {
+		if term == "" {
+			continue
+		}
+		sign := 1
+		if term[0] == '-' {
+			sign = -1
+			term = term[1:]
+		}
+		split := strings.SplitN(term, "d", 2)
+		n, err := strconv.Atoi(split[0])
+		if err != nil {
+			continue
+		}
+		if len(split) == 1 {
+			odds = shiftOdds(odds, sign*n)
+			continue
+		}
+		sides, err := strconv.Atoi(split[1])
+		if err != nil || sides <= 0 {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			odds = addDie(odds, sides, sign)
+		}
+	}
+	return odds
+}
+
+func shiftOdds(odds map[int]float64, n int) map[int]float64 {
+	shifted := make(map[int]float64, len(odds))
+	for total, p := range odds {
+		shifted[total+n] = p
+	}
+	return shifted
+}
+
+func addDie(odds map[int]float64, sides, sign int) map[int]float64 {
+	next := make(map[int]float64, len(odds)+sides)
+	for total, p := range odds {
+		for face := 1; face <= sides; face++ {
+			next[total+sign*face] += p / float64(sides)
+		}
+	}
+	return next
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiceOdds(t *testing.T) {
+	r := require.New(t)
+
+	odds := diceOdds(dicey.MustParse("2d3+1"))
+	r.Len(odds, 5)
+	r.InDelta(1.0/9, odds[3], 0.0001)
+	r.InDelta(3.0/9, odds[5], 0.0001)
+	r.InDelta(1.0/9, odds[7], 0.0001)
+
+	odds = diceOdds(dicey.MustParse("1d4-2"))
+	r.InDelta(0.25, odds[-1], 0.0001)
+	r.InDelta(0.25, odds[2], 0.0001)
+}
+
+func TestForecast(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := m.SpawnPoints[PlayerTeam][0]
+
+	source := &Mob{hp: 10, maxHP: 10, loc: at}
+	target := &Mob{hp: 4, maxHP: 10, loc: Loc{Map: m.Name, X: at.X, Y: at.Y - 1}, facing: South}
+	weapon := Weapon{Name: "test", Damage: Damage{Dice: dicey.MustParse("1d6")}, Range: 1, Magic: true}
+
+	fc := ForecastAttack(w, source, weapon, []*Mob{target})
+	r.Len(fc, 1)
+	r.Equal(100, fc[0].Hit)
+	r.Equal(1, fc[0].Min)
+	r.Equal(6, fc[0].Max)
+	r.Equal(4, fc[0].Avg)
+	r.Equal(0, fc[0].HP)
+	r.Equal(50, fc[0].Kill) // 4, 5 or 6
+
+	weapon.Damage.Type = DamageHealing
+	fc = ForecastAttack(w, source, weapon, []*Mob{target})
+	r.True(fc[0].Heals())
+	r.Equal(0, fc[0].Kill)
+	r.Equal(8, fc[0].HP)
+}
+
+func TestForecastAffinities(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := m.SpawnPoints[PlayerTeam][0]
+
+	source := &Mob{hp: 10, maxHP: 10, loc: at}
+	target := &Mob{hp: 10, maxHP: 20, loc: Loc{Map: m.Name, X: at.X, Y: at.Y - 1}}
+	weapon := Weapon{Name: "test", Damage: Damage{Dice: dicey.MustParse("10"), Type: DamageMagic, Element: ElementFire}, Magic: true}
+
+	fc := forecast(w, source, weapon, target)
+	r.Equal(10, fc.Avg)
+
+	target.affinities = Affinities{ElementFire: AffinityWeak}
+	r.Equal(15, forecast(w, source, weapon, target).Avg)
+	target.affinities[ElementFire] = AffinityResist
+	r.Equal(5, forecast(w, source, weapon, target).Avg)
+	target.affinities[ElementFire] = AffinityImmune
+	r.Equal(0, forecast(w, source, weapon, target).Avg)
+	target.affinities[ElementFire] = AffinityAbsorb
+	fc = forecast(w, source, weapon, target)
+	r.True(fc.Heals())
+	r.Equal(20, fc.HP)
+
+	// other elements aren't affected
+	weapon.Damage.Element = ElementIce
+	r.Equal(10, forecast(w, source, weapon, target).Avg)
+}
@@ -0,0 +1,227 @@
+package main
+
+// defaultVision is how far units can see in fog of war mode.
+const defaultVision = 7
+
+// Vision is what one team can see in fog of war mode.
+type Vision struct {
+	width   int
+	visible []bool // in sight right now
+	seen    []bool // seen at some point this battle
+
+	// where enemies were last spotted, so the AI can go look for them
+	lastSeen map[*Mob]Loc
+
+	// what this was computed from, see World.Vision
+	m       *Map
+	version int
+	turn    int64
+	eyes    []*Mob // who was looking, to notice when one dies or changes sides
+}
+
+func newVision(m *Map) *Vision {
+	size := m.Width() * m.Height()
+	return &Vision{
+		width:    m.Width(),
+		visible:  make([]bool, size),
+		seen:     make([]bool, size),
+		lastSeen: make(map[*Mob]Loc),
+		m:        m,
+		version:  -1,
+	}
+}
+
+// Visible returns true if the tile at x, y is in sight right now.
+func (v *Vision) Visible(x, y int) bool {
+	i := y*v.width + x
+	return x >= 0 && x < v.width && i >= 0 && i < len(v.visible) && v.visible[i]
+}
+
+// Seen returns true if the tile at x, y has been seen this battle.
+func (v *Vision) Seen(x, y int) bool {
+	i := y*v.width + x
+	return x >= 0 && x < v.width && i >= 0 && i < len(v.seen) && v.seen[i]
+}
+
+func (v *Vision) see(x, y int) {
+	i := y*v.width + x
+	if x < 0 || x >= v.width || i < 0 || i >= len(v.visible) {
+		return
+	}
+	v.visible[i] = true
+	v.seen[i] = true
+}
+
+// Vision returns what team can see right now, or nil if fog of war is off.
+// It's only recomputed when something on the map moves or changes, a unit on team dies or changes sides,
+// or a new turn starts.
+func (w *World) Vision(team int) *Vision {
+	if !w.fog || w.current == nil {
+		return nil
+	}
+	if w.visions == nil {
+		w.visions = make(map[int]*Vision)
+	}
+	m := w.current
+	v := w.visions[team]
+	if v == nil || v.m != m {
+		v = newVision(m)
+		w.visions[team] = v
+	}
+	if v.version == m.version && v.turn == w.turn && !v.eyesChanged(m, team) {
+		return v
+	}
+	v.version = m.version
+	v.turn = w.turn
+	v.update(m, team)
+	return v
+}
+
+// eyesChanged returns true if someone on team has died, or someone has joined or left it, since the last update.
+func (v *Vision) eyesChanged(m *Map, team int) bool {
+	for _, mob := range v.eyes {
+		if mob.Dead() || mob.Team() != team {
+			return true
+		}
+	}
+	n := 0
+	for _, obj := range m.Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() && mob.Team() == team {
+			n++
+		}
+	}
+	return n != len(v.eyes)
+}
+
+// update recasts what team can see on m, and where enemies were last seen.
+func (v *Vision) update(m *Map, team int) {
+	for i := range v.visible {
+		v.visible[i] = false
+	}
+	v.eyes = v.eyes[:0]
+	for _, obj := range m.Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() && mob.Team() == team {
+			loc := mob.Loc()
+			m.FOV(loc.X, loc.Y, mob.Vision(), v.see)
+			v.eyes = append(v.eyes, mob)
+		}
+	}
+	for _, obj := range m.Objects {
+		if mob, ok := obj.(*Mob); ok && mob.Team() != team && v.Visible(mob.Loc().X, mob.Loc().Y) {
+			v.lastSeen[mob] = mob.Loc()
+		}
+	}
+	for mob, loc := range v.lastSeen {
+		// forget about the dead, and anyone who isn't where we last saw them
+		if mob.Dead() || mob.Team() == team || (v.Visible(loc.X, loc.Y) && mob.Loc() != loc) {
+			delete(v.lastSeen, mob)
+		}
+	}
+}
+
+// CanSee returns true if team can see what's at loc. It's always true with fog of war off.
+func (w *World) CanSee(team int, loc Loc) bool {
+	v := w.Vision(team)
+	return v == nil || v.Visible(loc.X, loc.Y)
+}
+
+// Sees returns true if team can see obj. Units can always see their own team and spells.
+func (w *World) Sees(team int, obj Object) bool {
+	switch obj := obj.(type) {
+	case *Mob:
+		if obj.Team() == team {
+			return true
+		}
+	case *Charge:
+		if obj.Caster().Team() == team {
+			return true
+		}
+	}
+	return w.CanSee(team, obj.Loc())
+}
+
+// blocksSight returns true if the tile at x, y blocks the view of someone whose eyes are at height eye.
+func (m *Map) blocksSight(x, y, eye int) bool {
+	if x < 0 || y < 0 || x >= m.Width() || y >= m.Height() {
+		return true
+	}
+	tile := m.TileAt(x, y)
+	return !tile.IsValid() || tile.Collides || tile.Height > eye
+}
+
+// octants transform coordinates so castLight can cover the whole circle one eighth at a time.
+var octants = [8][4]int{
+	{1, 0, 0, 1},
+	{0, 1, 1, 0},
+	{0, -1, 1, 0},
+	{-1, 0, 0, 1},
+	{-1, 0, 0, -1},
+	{0, -1, -1, 0},
+	{0, 1, -1, 0},
+	{1, 0, 0, -1},
+}
+
+// FOV calls see for every tile in sight of x, y within radius, using recursive shadowcasting.
+// Walls and terrain taller than eye level block the view, but are themselves visible.
+func (m *Map) FOV(x, y, radius int, see func(x, y int)) {
+	if x < 0 || y < 0 || x >= m.Width() || y >= m.Height() {
+		return
+	}
+	see(x, y)
+	eye := m.TileAt(x, y).Height + 1
+	for _, oct := range octants {
+		m.castLight(x, y, 1, 1.0, 0.0, radius, eye, oct, see)
+	}
+}
+
+func (m *Map) castLight(cx, cy, row int, start, end float64, radius, eye int, oct [4]int, see func(x, y int)) {
+	if start < end {
+		return
+	}
+	xx, xy, yx, yy := oct[0], oct[1], oct[2], oct[3]
+	radius2 := radius*radius + radius
+	for j := row; j <= radius; j++ {
+		dx, dy := -j-1, -j
+		blocked := false
+		newStart := start
+		for dx <= 0 {
+			dx++
+			x, y := cx+dx*xx+dy*xy, cy+dx*yx+dy*yy
+			left := (float64(dx) - 0.5) / (float64(dy) + 0.5)
+			right := (float64(dx) + 0.5) / (float64(dy) - 0.5)
+			if start < right {
+				continue
+			}
+			if end > left {
+				break
+			}
+			if dx*dx+dy*dy <= radius2 && x >= 0 && y >= 0 && x < m.Width() && y < m.Height() {
+				see(x, y)
+			}
+			opaque := m.blocksSight(x, y, eye)
+			if blocked {
+				if opaque {
+					newStart = right
+					continue
+				}
+				blocked = false
+				start = newStart
+			} else if opaque && j < radius {
+				blocked = true
+				m.castLight(cx, cy, j+1, start, left, radius, eye, oct, see)
+				newStart = right
+			}
+		}
+		if blocked {
+			break
+		}
+	}
+}
+
+// dimmed is how remembered tiles that aren't in sight look in fog of war mode.
+func dimmed(g Glyph) Glyph {
+	g.FG = Color256(239)
+	g.BG = nil
+	g.Bold = false
+	return g
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFOV(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	w.fog = true
+
+	scout := spawn(w, 41, 6, &Mob{maxHP: 50, team: PlayerTeam})
+	hiding := spawn(w, 41, 2, &Mob{maxHP: 50, team: 1})
+	far := spawn(w, 50, 13, &Mob{maxHP: 50, team: 1})
+
+	v := w.Vision(PlayerTeam)
+	r.True(v.Visible(41, 5))
+	r.True(v.Visible(41, 4), "walls are visible")
+	r.False(v.Visible(41, 2), "but you can't see past them")
+	r.False(v.Visible(50, 13), "out of range")
+	r.False(v.Visible(41, 16), "outside the room")
+	r.True(w.Sees(PlayerTeam, scout))
+	r.False(w.Sees(PlayerTeam, hiding))
+	r.False(w.Sees(PlayerTeam, far))
+
+	// vision is cached until something moves or the turn changes
+	r.Same(v, w.Vision(PlayerTeam))
+	v.visible[5*v.width+41] = false
+	r.False(w.Vision(PlayerTeam).Visible(41, 5))
+	w.turn++
+	r.True(w.Vision(PlayerTeam).Visible(41, 5))
+	// or someone dies or changes sides
+	scout.hp = 0
+	r.False(w.Vision(PlayerTeam).Visible(41, 5))
+	scout.hp = 50
+	r.True(w.Vision(PlayerTeam).Visible(41, 5))
+	scout.team = 1
+	r.False(w.Vision(PlayerTeam).Visible(41, 5))
+	r.True(w.Vision(1).Visible(41, 5))
+	scout.team = PlayerTeam
+
+	// tiles stay remembered after leaving
+	m.Move(scout, 36, 13)
+	v = w.Vision(PlayerTeam)
+	r.False(v.Visible(41, 5))
+	r.True(v.Seen(41, 5))
+	r.False(v.Seen(41, 2))
+
+	// the enemy remembers where it saw you
+	m.Move(far, 42, 13)
+	r.True(w.Sees(1, scout))
+	m.Move(far, 50, 5)
+	r.False(w.Sees(1, scout))
+	r.Equal(scout.Loc(), w.Vision(1).lastSeen[scout])
+
+	// fog of war is optional
+	w.fog = false
+	r.Nil(w.Vision(PlayerTeam))
+	r.True(w.Sees(PlayerTeam, hiding))
+}
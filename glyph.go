@@ -178,6 +178,15 @@ func Concat(args ...interface{}) []Glyph {
 	return result
 }
 
+// PlainText returns the text of glyphs without any colors or styles.
+func PlainText(glyphs []Glyph) string {
+	runes := make([]rune, len(glyphs))
+	for i, g := range glyphs {
+		runes[i] = g.Rune
+	}
+	return string(runes)
+}
+
 func ApplyStyle(glyphs []Glyph, styles ...Style) {
 	for i := range glyphs {
 		g := &glyphs[i]
@@ -7,7 +7,6 @@ require (
 	github.com/gliderlabs/ssh v0.1.3
 	github.com/guregu/dicey v1.1.0
 	github.com/kr/pretty v0.2.0 // indirect
-	github.com/nickdavies/go-astar v0.0.0-20140108070343-e72e60e44619
 	github.com/stretchr/testify v1.5.1
 	github.com/ztrue/shutdown v0.1.1
 	golang.org/x/crypto v0.0.0-20190228161510-8dd112bcdc25 // indirect
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWorld makes a bare World with just the given map loaded and nothing on it.
+// The map is put back the way it was when the test is done.
+func newTestWorld(t *testing.T, mapName string) (*World, *Map) {
+	t.Helper()
+	m, err := loadMap(mapName)
+	require.NoError(t, err)
+	t.Cleanup(m.Reset)
+	w := &World{
+		maps:       map[string]*Map{m.Name: m},
+		objects:    make(map[ID]Object),
+		busy:       new(int32),
+		current:    m,
+		push:       make(chan StateAction, 8),
+		pushBottom: make(chan StateAction, 8),
+	}
+	return w, m
+}
+
+// spawn puts mob on the world's map at x, y, fully healed. Units without a max HP get 20.
+func spawn(w *World, x, y int, mob *Mob) *Mob {
+	if mob.maxHP == 0 {
+		mob.maxHP = 20
+	}
+	mob.loc = Loc{Map: w.current.Name, X: x, Y: y}
+	mob.Reset(w)
+	w.Add(mob)
+	return mob
+}
+
+// testConn is a connection that goes nowhere, for sessions in tests.
+type testConn struct {
+	bytes.Buffer
+}
+
+func (*testConn) Exit(int) error {
+	return nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"strings"
 	"time"
@@ -34,6 +36,11 @@ type Sesh struct {
 	disp  *Display
 
 	cursor Coords
+
+	// accessible mode, see SetAccessible
+	accessible bool
+	lastTurn   int64  // the last turn announced
+	looked     Coords // the last tile announced under the cursor
 }
 
 func NewSesh(s Conn, w *World) *Sesh {
@@ -41,6 +48,8 @@ func NewSesh(s Conn, w *World) *Sesh {
 		world: w,
 		ssh:   s,
 		disp:  NewDisplay(80, 27),
+
+		looked: InvalidCoords,
 	}
 }
 
@@ -91,10 +100,14 @@ func (sesh *Sesh) refresh() {
 		return
 	}
 
+	sesh.announceChanges()
 	scr := sesh.disp.nextFrame()
 	for i := 0; i < len(sesh.ui); i++ {
 		sesh.ui[i].Render(scr)
 	}
+	if sesh.accessible {
+		unblink(scr)
+	}
 	render := sesh.disp.diff()
 	if render == "" {
 		cursor := sesh.ui[len(sesh.ui)-1].Cursor()
@@ -115,10 +128,14 @@ func (sesh *Sesh) redraw() {
 		return
 	}
 
+	sesh.announceChanges()
 	scr := sesh.disp.nextFrame()
 	for i := 0; i < len(sesh.ui); i++ {
 		sesh.ui[i].Render(scr)
 	}
+	if sesh.accessible {
+		unblink(scr)
+	}
 	io.WriteString(sesh.ssh, sesh.disp.full())
 	sesh.renderCursor(sesh.ui[len(sesh.ui)-1].Cursor())
 }
@@ -128,11 +145,14 @@ func (sesh *Sesh) renderCursor(coords Coords) {
 }
 
 func (sesh *Sesh) Send(msg []Glyph) {
+	if sesh.accessible {
+		msg = GlyphsOf(PlainText(msg))
+	}
 	sesh.win.Msgs = append(sesh.win.Msgs, msg)
 }
 
 func (sesh *Sesh) Bell() {
-	io.WriteString(sesh.ssh, string(7))
+	io.WriteString(sesh.ssh, "\a")
 }
 
 func (sesh *Sesh) setup() {
@@ -145,6 +165,14 @@ func (sesh *Sesh) PushWindow(win Window) {
 	sesh.ui = append(sesh.ui, win)
 }
 
+// TopWindow returns the window on top, which gets input first.
+func (sesh *Sesh) TopWindow() Window {
+	if len(sesh.ui) == 0 {
+		return nil
+	}
+	return sesh.ui[len(sesh.ui)-1]
+}
+
 func (sesh *Sesh) cleanup() {
 	sesh.world.apply <- PartAction{listener: sesh}
 	// fmt.Println("disconnex")
@@ -170,9 +198,16 @@ func (sesh *Sesh) Run() {
 }
 
 func main() {
+	botAddr := flag.String("bot", "", `serve the bot protocol on "stdio", "tcp:host:port" or "unix:/path" (see bot.go)`)
+	flag.Parse()
 	rand.Seed(time.Now().UnixNano())
 
 	world := newWorld()
+	if *botAddr != "" {
+		if err := serveBots(world, *botAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
 	go world.Run()
 
 	handleSSH(world)
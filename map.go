@@ -10,8 +10,6 @@ import (
 	"encoding/json"
 	"math/rand"
 	"strings"
-
-	"github.com/nickdavies/go-astar/astar"
 )
 
 type Map struct {
@@ -22,6 +20,18 @@ type Map struct {
 	SpawnPoints [][]Loc
 
 	Meta MapMeta
+
+	// cached grids for pathfinding
+	walkGrid []int
+	flyGrid  []int
+	heights  []int
+
+	// original tiles that were broken or used, put back on Reset
+	changed map[*Tile]Tile
+
+	// bumped whenever objects come, go or move, or tiles change,
+	// so things computed from the map (like Vision) know when they're stale
+	version int
 }
 
 type MapMeta struct {
@@ -30,10 +40,15 @@ type MapMeta struct {
 	Height int
 	Glyphs map[string]MetaGlyphDef
 	BG     [][]Color256
+	// Heights is a layer of tile elevations, one digit per tile.
+	// Anything else falls back to the glyph's height.
+	Heights []string
 
 	Teams       int
 	SpawnPoints [][][2]int
 	SpawnGlyphs []string
+
+	Traps []MetaTrap
 }
 
 type MetaGlyphDef struct {
@@ -41,6 +56,55 @@ type MetaGlyphDef struct {
 	BG      Color
 	Collide bool
 	Replace string
+	Terrain
+	Feature
+}
+
+// Terrain describes how a tile affects the units on it.
+type Terrain struct {
+	Name    string
+	Cost    int  // movement cost, 0 = 1
+	Defense int  // defense bonus for units standing here
+	Evasion int  // evasion bonus (percent) for units standing here
+	FlyOnly bool // impassable for walkers, but not flyers
+	Height  int  // elevation, see also MapMeta.Heights
+	Hazard  int  // damage to walkers knocked into this tile
+	Cover   int  // hit chance penalty (percent) for shots past or through this tile, 0 = default, -1 = none
+}
+
+// HasEffects returns true if this terrain is any different from plain floor.
+func (t Terrain) HasEffects() bool {
+	return t.MoveCost() != 1 || t.Defense != 0 || t.Evasion != 0 || t.FlyOnly || t.Hazard != 0 || t.Cover > 0
+}
+
+func (t Terrain) MoveCost() int {
+	if t.Cost <= 0 {
+		return 1
+	}
+	return t.Cost
+}
+
+func (t Terrain) String() string {
+	var info []string
+	if t.MoveCost() != 1 {
+		info = append(info, fmt.Sprintf("move cost %d", t.MoveCost()))
+	}
+	if t.Defense != 0 {
+		info = append(info, fmt.Sprintf("%+dAC", t.Defense))
+	}
+	if t.Evasion != 0 {
+		info = append(info, fmt.Sprintf("%+d%% evasion", t.Evasion))
+	}
+	if t.FlyOnly {
+		info = append(info, "flyers only")
+	}
+	if t.Hazard != 0 {
+		info = append(info, fmt.Sprintf("hazard %d", t.Hazard))
+	}
+	if t.Cover > 0 {
+		info = append(info, fmt.Sprintf("cover %d%%", t.Cover))
+	}
+	return strings.Join(info, ", ")
 }
 
 func (gd *MetaGlyphDef) UnmarshalJSON(data []byte) error {
@@ -66,6 +130,8 @@ func (gd *MetaGlyphDef) UnmarshalJSON(data []byte) error {
 		FG, BG  json.RawMessage
 		Collide bool
 		Replace string
+		Terrain
+		Feature
 	}{}
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
@@ -74,9 +140,87 @@ func (gd *MetaGlyphDef) UnmarshalJSON(data []byte) error {
 	gd.BG = convert(raw.BG)
 	gd.Collide = raw.Collide
 	gd.Replace = raw.Replace
+	gd.Terrain = raw.Terrain
+	gd.Feature = raw.Feature
 	return nil
 }
 
+// merge overlays the set fields of other on top of t,
+// so glyphs can be described by more than one entry in the map metadata.
+func (t *Terrain) merge(other Terrain) {
+	if other.Name != "" {
+		t.Name = other.Name
+	}
+	if other.Cost != 0 {
+		t.Cost = other.Cost
+	}
+	if other.Defense != 0 {
+		t.Defense = other.Defense
+	}
+	if other.Evasion != 0 {
+		t.Evasion = other.Evasion
+	}
+	if other.FlyOnly {
+		t.FlyOnly = true
+	}
+	if other.Height != 0 {
+		t.Height = other.Height
+	}
+	if other.Hazard != 0 {
+		t.Hazard = other.Hazard
+	}
+	if other.Cover != 0 {
+		t.Cover = other.Cover
+	}
+}
+
+// build sets up tile as the given glyph, according to the map metadata.
+func (m *Map) build(tile *Tile, r rune) {
+	meta := m.Meta
+	x, y := tile.X, tile.Y
+	glyph := GlyphOf(r)
+	collides := false
+	var terrain Terrain
+	var feature Feature
+	var replace string
+	for glyphs, info := range meta.Glyphs {
+		if !strings.ContainsRune(glyphs, r) {
+			continue
+		}
+		if info.Collide {
+			collides = info.Collide
+		}
+		terrain.merge(info.Terrain)
+		feature.merge(info.Feature)
+		if info.FG != nil {
+			glyph.FG = info.FG
+		}
+		if info.BG != nil {
+			glyph.BG = info.BG
+		}
+		if info.Replace != "" {
+			replace += info.Replace
+		}
+	}
+	if meta.BG != nil {
+		glyph.BG = meta.BG[y][x]
+	}
+	if y < len(meta.Heights) {
+		if row := []rune(meta.Heights[y]); x < len(row) && row[x] >= '0' && row[x] <= '9' {
+			terrain.Height = int(row[x] - '0')
+		}
+	}
+	if len(replace) > 0 {
+		runes := []rune(replace)
+		glyph.Rune = runes[rand.Intn(len(runes))]
+	}
+	tile.Ground = glyph
+	tile.Collides = collides
+	tile.Terrain = terrain
+	tile.Feature = feature
+	tile.hp = feature.HP
+}
+
 func (m *Map) NewTile(glyph Glyph, collides bool, x, y int) *Tile {
 	return &Tile{
 		Ground:   glyph,
@@ -96,6 +240,7 @@ func (m *Map) Reset() {
 			m.Tiles[y][x].RemoveAll()
 		}
 	}
+	m.restore()
 }
 
 func (m *Map) TileAtLoc(loc Loc) *Tile {
@@ -120,11 +265,13 @@ func (m *Map) TileAt(x, y int) *Tile {
 }
 
 func (m *Map) Add(obj Object) {
+	m.version++
 	m.Objects[obj.ID()] = obj
 	m.TileAtLoc(obj.Loc()).Add(obj)
 }
 
 func (m *Map) Remove(obj Object) {
+	m.version++
 	delete(m.Objects, obj.ID())
 	m.TileAtLoc(obj.Loc()).Remove(obj)
 }
@@ -133,6 +280,7 @@ func (m *Map) Move(obj Object, x, y int) {
 	if x < 0 || y < 0 {
 		return
 	}
+	m.version++
 	loc := obj.Loc()
 	m.TileAtLoc(loc).Remove(obj)
 	loc.X = x
@@ -154,59 +302,6 @@ func (m *Map) Width() int {
 	return len(m.Tiles[0])
 }
 
-func (m *Map) FindPath(fromX, fromY, toX, toY int, ignore ...Object) []Loc {
-	a := m.astar(ignore...)
-	p2p := astar.NewPointToPoint()
-	path := a.FindPath(p2p, []astar.Point{astar.Point{fromY, fromX}}, []astar.Point{astar.Point{toY, toX}})
-
-	var locs []Loc
-	for path != nil {
-		if !(path.Col == fromX && path.Row == fromY) {
-			locs = append(locs, Loc{Map: m.Name, X: path.Col, Y: path.Row})
-		}
-		path = path.Parent
-	}
-	return locs
-}
-
-func (m *Map) astar(ignore ...Object) astar.AStar {
-	a := astar.NewAStar(m.Height(), m.Width())
-	for y := 0; y < m.Height(); y++ {
-		for x := 0; x < m.Width(); x++ {
-			tile := m.TileAt(x, y)
-			if tile.Collides {
-				a.FillTile(astar.Point{y, x}, -1)
-			} else if tile.HasCollider(ignore...) {
-				a.FillTile(astar.Point{y, x}, -1)
-			}
-		}
-	}
-	return a
-}
-
-func (m *Map) FindPathNextTo(from *Mob, to *Mob) []Loc {
-	fromLoc := from.Loc()
-	toLoc := to.Loc()
-
-	n := m.FindPath(fromLoc.X, fromLoc.Y, toLoc.X, toLoc.Y-1, from)
-	e := m.FindPath(fromLoc.X, fromLoc.Y, toLoc.X+1, toLoc.Y, from)
-	s := m.FindPath(fromLoc.X, fromLoc.Y, toLoc.X, toLoc.Y+1, from)
-	w := m.FindPath(fromLoc.X, fromLoc.Y, toLoc.X-1, toLoc.Y, from)
-
-	path := n
-	if e != nil && (path == nil || len(e) < len(path)) {
-		path = e
-	}
-	if s != nil && (path == nil || len(s) < len(path)) {
-		path = s
-	}
-	if w != nil && (path == nil || len(w) < len(path)) {
-		path = w
-	}
-
-	return path
-}
-
 func (m *Map) Raycast(from, to Loc, ignoreObstacles bool) (hit *Mob, blocked bool, path []Loc) {
 	x0 := from.X
 	y0 := from.Y
@@ -234,6 +329,11 @@ func (m *Map) Raycast(from, to Loc, ignoreObstacles bool) (hit *Mob, blocked boo
 	}
 	err := dx - dy
 
+	// the line of sight goes from eye level to eye level,
+	// and anything on the way that sticks out above it blocks the shot
+	fromHeight, toHeight := m.TileAtLoc(from).Height+1, m.TileAtLoc(to).Height+1
+	steps := max(dx, dy)
+
 	for {
 		if !(x0 == from.X && y0 == from.Y) {
 			path = append(path, Loc{Map: m.Name, X: x0, Y: y0})
@@ -248,6 +348,12 @@ func (m *Map) Raycast(from, to Loc, ignoreObstacles bool) (hit *Mob, blocked boo
 			if !ignoreObstacles && tile.Collides {
 				return nil, true, path
 			}
+			if !ignoreObstacles && !(x0 == x1 && y0 == y1) {
+				sight := fromHeight + (toHeight-fromHeight)*len(path)/steps
+				if tile.Height > sight {
+					return nil, true, path
+				}
+			}
 		}
 		if x0 == x1 && y0 == y1 {
 			break
@@ -272,6 +378,10 @@ type Tile struct {
 	X, Y     int
 	Map      *Map
 	invalid  bool
+
+	Terrain
+	Feature
+	hp int // for destructible tiles
 }
 
 func (t *Tile) Add(obj Object) {
@@ -286,10 +396,22 @@ func (t *Tile) RemoveAll() {
 	t.Objects = make(map[ID]Object)
 }
 
+// List returns a copy of the objects on the tile, for looping over when they might come and go along the way.
+func (t *Tile) List() []Object {
+	objs := make([]Object, 0, len(t.Objects))
+	for _, obj := range t.Objects {
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
 func (t *Tile) Top() Object {
 	z := -1
 	var obj Object
 	for _, o := range t.Objects {
+		if h, ok := o.(interface{ Hidden() bool }); ok && h.Hidden() {
+			continue
+		}
 		loc := o.Loc()
 		if loc.Z > z {
 			obj = o
@@ -327,6 +449,56 @@ func (t *Tile) Glyph() Glyph {
 	return t.Ground
 }
 
+// Passable returns true if a walker (or flyer) can enter this tile, ignoring objects.
+func (t *Tile) Passable(flying bool) bool {
+	if t.Collides {
+		return false
+	}
+	return flying || !t.FlyOnly
+}
+
+// TerrainName returns the name of this tile's terrain, like "floor" or "wall".
+func (t *Tile) TerrainName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if t.Collides {
+		if t.Ground.Rune == ' ' {
+			return "empty space"
+		}
+		return "wall"
+	}
+	return "floor"
+}
+
+// Describe returns the name of this tile's terrain and its effects.
+func (t *Tile) Describe() string {
+	name := t.TerrainName()
+	info := t.Terrain.String()
+	if t.Height != 0 {
+		if info != "" {
+			info += ", "
+		}
+		info += fmt.Sprintf("height %d", t.Height)
+	}
+	if t.Destructible() {
+		if info != "" {
+			info += ", "
+		}
+		info += fmt.Sprintf("%d/%d HP", t.hp, t.Feature.HP)
+	}
+	if t.Usable() {
+		if info != "" {
+			info += ", "
+		}
+		info += "can " + t.Use
+	}
+	if info != "" {
+		name += " (" + info + ")"
+	}
+	return name
+}
+
 func (t *Tile) String() string {
 	return fmt.Sprintf("Tile(%s:%d,%d)", t.Map.Name, t.X, t.Y)
 }
@@ -350,6 +522,9 @@ func loadMap(name string) (*Map, error) {
 		if err := json.NewDecoder(metaf).Decode(&meta); err != nil {
 			return nil, err
 		}
+		if err := checkTraps(meta.Traps); err != nil {
+			return nil, fmt.Errorf("map %s: %v", name, err)
+		}
 	}
 	// log.Printf("Loading map: %s %+v", name, meta)
 
@@ -387,36 +562,8 @@ func loadMap(name string) (*Map, error) {
 						r = '.'
 					}
 				}
-				glyph := GlyphOf(r)
-				collides := false
-				var replace string
-				for glyphs, info := range meta.Glyphs {
-					if !strings.ContainsRune(glyphs, r) {
-						continue
-					}
-					if info.Collide {
-						collides = info.Collide
-					}
-					if info.FG != nil {
-						glyph.FG = info.FG
-					}
-					if info.BG != nil {
-						glyph.BG = info.BG
-					}
-					if info.Replace != "" {
-						fmt.Println("REPPP", info.Replace)
-						replace += info.Replace
-					}
-				}
-				if meta.BG != nil {
-					glyph.BG = meta.BG[y][x]
-				}
-				if len(replace) > 0 {
-					fmt.Println("REPLACE", replace)
-					runes := []rune(replace)
-					glyph.Rune = runes[rand.Intn(len(runes))]
-				}
-				tile := m.NewTile(glyph, collides, x, y)
+				tile := m.NewTile(GlyphOf(r), false, x, y)
+				m.build(tile, r)
 				tline = append(tline, tile)
 				x++
 			}
@@ -0,0 +1,785 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// In hard mode, instead of going with whatever plan scores best at a glance,
+// the AI searches through the next several turns of the battle with Monte Carlo tree search.
+// The battle is copied into a simplified simulation first, so the search can run off the world goroutine
+// while animations keep playing.
+//
+// The tree starts with the AI's best few plans for its own turn. Below those, every turn of the battle
+// is a choice between the few most promising things that unit could do, made by whoever's turn it is
+// in favor of their own team. Each playout walks down the tree, picking the branches that have worked out well
+// and haven't been tried much (UCB1), adds one new branch, and plays greedily from there.
+// The dice are rolled fresh every playout rather than stored in the tree (open loop),
+// so each branch's average is what to expect from it over all the ways the dice can land.
+// Moves that stop being possible because of how the dice landed, like attacking someone who's already dead,
+// are skipped for that playout.
+
+const (
+	searchCandidates = 8   // how many of the best scoring plans to choose between
+	rolloutTurns     = 12  // how many turns each playout looks ahead, in the tree and after it
+	exploration      = 1.4 // how much the search favors trying moves it hasn't played out much
+	maxBranch        = 6   // how many moves the search considers for everyone's turns after ours
+	maxSimMoves      = 4   // a unit's weapon and its first few spells
+	statusWorth      = 0.3 // how much the simulated units like putting a status on someone, see simBattle.worth
+	blindPenalty     = 0.4 // what blind takes off hit chances
+	protectDefense   = 2   // what protect takes off each hit
+)
+
+// thinkTimes are the difficulty settings: how long the AI thinks each turn. 0 = normal.
+var thinkTimes = []time.Duration{0, 500 * time.Millisecond, time.Second, 2 * time.Second}
+
+// simStatus is a buff or status effect the simulation knows how to play out.
+type simStatus int
+
+const (
+	simNone simStatus = iota
+	simHaste
+	simSlow
+	simSleep
+	simStun
+	simCripple
+	simCharm
+	simSilence
+	simBlind
+	simBerserk
+	simPoison
+	simRegen
+	simRenew
+	simProtect
+	simStatusCount
+)
+
+// simStatusInfo is how a status plays out in the simulation.
+type simStatusInfo struct {
+	name        string
+	min, max    int     // how many turns it lasts when applied
+	breakChance float64 // chance to wear off early at the start of each turn
+	dot         [2]int  // damage each turn, like Buff.DoT: negative heals at the start of turns, positive hurts at the end
+}
+
+// simStatuses follow the buffs in status.go and spell.go.
+var simStatuses = [simStatusCount]simStatusInfo{
+	simHaste:   {name: "haste", min: 3, max: 4},
+	simSlow:    {name: "slow", min: 3, max: 4},
+	simSleep:   {name: "sleep", min: 2, max: 3},
+	simStun:    {name: "stun", min: 1, max: 1},
+	simCripple: {name: "cripple", min: 2, max: 7, breakChance: 0.1},
+	simCharm:   {name: "charm", min: 1, max: 2},
+	simSilence: {name: "silence", min: 2, max: 4},
+	simBlind:   {name: "blind", min: 2, max: 4},
+	simBerserk: {name: "berserk", min: 2, max: 3},
+	simPoison:  {name: "poison", min: 4, max: 6, breakChance: 0.1, dot: [2]int{2, 5}},
+	simRegen:   {name: "regen", min: 3, max: 4, dot: [2]int{-3, -5}},
+	simRenew:   {name: "renew", min: 4, max: 6, dot: [2]int{-2, -5}},
+	simProtect: {name: "protect", min: 3, max: 5},
+}
+
+// simStatusOf returns the first of buffs the simulation knows about, or simNone.
+func simStatusOf(buffs ...string) simStatus {
+	for _, name := range buffs {
+		for st, info := range simStatuses {
+			if st != int(simNone) && info.name == name {
+				return simStatus(st)
+			}
+		}
+	}
+	return simNone
+}
+
+// simUnit is a unit in a simulated battle.
+type simUnit struct {
+	mob        *Mob
+	team, home int // home is the team it started the battle on, which it goes back to when a charm wears off
+	x, y       int
+	hp, maxHP  int
+	mp, maxMP  int
+	mpRegen    int
+	ct, speed  int // speed before haste and slow
+	move       int
+	flying     bool
+	status     [simStatusCount]int // turns left on each status like Buff.Life: 0 = doesn't have it, -1 = until cured
+	cooldown   [maxSimMoves]int
+}
+
+func (u *simUnit) dead() bool {
+	return u.hp <= 0
+}
+
+func (u *simUnit) has(st simStatus) bool {
+	return u.status[st] != 0
+}
+
+func (u *simUnit) canAct() bool {
+	return !u.has(simSleep) && !u.has(simStun)
+}
+
+func (u *simUnit) canMove() bool {
+	return !u.has(simSleep) && !u.has(simCripple)
+}
+
+// curSpeed is the unit's speed with haste and slow, like their Affect funcs.
+func (u *simUnit) curSpeed() int {
+	speed := u.speed
+	if u.has(simHaste) {
+		speed += max(1, speed/2)
+	}
+	if u.has(simSlow) {
+		speed = max(1, speed/2)
+	}
+	return speed
+}
+
+// simEffect is what an attack might do to one unit.
+type simEffect struct {
+	target   int
+	hit      float64
+	min, max int // negative for heals
+	status   simStatus
+}
+
+// simMove is something a unit can do on its turn: attack with its weapon, or cast one of its spells.
+type simMove struct {
+	name     string
+	reach    int
+	mp       int
+	cooldown int
+	spell    bool
+	odds     []simEffect // odds[j] is what this does to unit j
+}
+
+// simAction is one turn in the simulation: move to dest, then use move on target. move is -1 to not act.
+type simAction struct {
+	dest         int
+	move, target int
+}
+
+// simNode is one turn in the search tree, and how it's worked out so far
+// for the team of the unit that took it.
+type simNode struct {
+	act      simAction
+	visits   int
+	total    float64
+	children []*simNode
+}
+
+// simChoice is one of the plans being searched at the top of the tree.
+type simChoice struct {
+	simNode
+	plan    *aiPlan
+	x, y    int
+	effects []simEffect
+}
+
+// simBattle is a copy of a battle with just enough in it to play out turns without touching the world.
+// Units walk around on the map's move costs, hit each other with their weapons and spells, and put buffs
+// and status effects on each other, with the odds taken from the real forecasts when the copy was made.
+// Terrain heights, facing, reactions, area of effect beyond our own turn, and status effects
+// the simulation doesn't know about are ignored.
+type simBattle struct {
+	width     int
+	walk, fly []int // move costs, -1 = impassable
+	units     []simUnit
+	moves     [][]simMove // moves[i] is what unit i can do, its weapon first
+	rng       *rand.Rand
+
+	dist  []int // scratch space for reachable
+	queue []int
+}
+
+// newSimBattle copies the battle as ai's unit sees it.
+// It has to be called on the world goroutine, but the copy can be used anywhere.
+func newSimBattle(w *World, ai *EnemyAIState) *simBattle {
+	self := ai.self
+	m := w.Map(self.Loc().Map)
+	s := &simBattle{
+		width: m.Width(),
+		walk:  append([]int(nil), m.costGrid(false)...),
+		fly:   append([]int(nil), m.costGrid(true)...),
+		rng:   rand.New(rand.NewSource(rand.Int63())),
+	}
+	for _, obj := range m.Objects {
+		mob, ok := obj.(*Mob)
+		if !ok || mob.Dead() || !w.Sees(self.Team(), mob) {
+			continue
+		}
+		loc := mob.Loc()
+		u := simUnit{
+			mob:     mob,
+			team:    mob.Team(),
+			home:    homeTeam(w, mob),
+			x:       loc.X,
+			y:       loc.Y,
+			hp:      mob.HP(),
+			maxHP:   max(mob.MaxHP(), 1),
+			mp:      mob.MP(),
+			maxMP:   mob.MaxMP(),
+			mpRegen: mob.Armor().MPRecovery + 1,
+			ct:      mob.CT(),
+			speed:   mob.base.Speed,
+			move:    mob.stats.Move,
+			flying:  mob.Flying(),
+		}
+		for buff := range mob.buffs {
+			if st := simStatusOf(buff.Name); st != simNone {
+				u.status[st] = buff.Life
+			}
+		}
+		s.units = append(s.units, u)
+	}
+	s.moves = make([][]simMove, len(s.units))
+	for i, a := range s.units {
+		weapons := append([]Weapon{a.mob.Weapon()}, a.mob.Spells()...)
+		for k, wep := range weapons {
+			if len(s.moves[i]) == maxSimMoves {
+				break
+			}
+			move := simMove{
+				name:     wep.Name,
+				reach:    max(wep.Range, 1),
+				mp:       wep.MPCost,
+				cooldown: wep.Cooldown,
+				spell:    k > 0,
+				odds:     make([]simEffect, len(s.units)),
+			}
+			useful := false
+			for j, b := range s.units {
+				move.odds[j] = s.effect(i, j, forecast(w, a.mob, wep, b.mob))
+				useful = useful || move.odds[j].hit > 0
+			}
+			if !useful {
+				continue
+			}
+			if move.spell {
+				s.units[i].cooldown[len(s.moves[i])] = a.mob.Cooldown(wep)
+			}
+			s.moves[i] = append(s.moves[i], move)
+		}
+	}
+	s.dist = make([]int, len(s.walk))
+	return s
+}
+
+// homeTeam returns the team mob started the battle on.
+func homeTeam(w *World, mob *Mob) int {
+	for _, team := range w.battle.Teams {
+		for _, m := range team.Units {
+			if m == mob {
+				return team.ID
+			}
+		}
+	}
+	return mob.Team()
+}
+
+// effect turns a forecast of unit i attacking unit j into a simEffect.
+// Blind and protect are taken back out of the odds, since roll puts them back in for as long as they last.
+// Attacks that wouldn't do anything the simulation knows about get no chance to hit.
+func (s *simBattle) effect(i, j int, fc Forecast) simEffect {
+	a, b := &s.units[i], &s.units[j]
+	fx := simEffect{target: j, hit: float64(fc.Hit) / 100, min: fc.Min, max: fc.Max, status: simStatusOf(fc.Buffs...)}
+	if a.has(simBlind) {
+		fx.hit = math.Min(fx.hit+blindPenalty, 1)
+	}
+	if b.has(simProtect) && fx.max > 0 {
+		fx.min += protectDefense
+		fx.max += protectDefense
+	}
+	if fx.min == 0 && fx.max == 0 && fx.status == simNone {
+		fx.hit = 0
+	}
+	return fx
+}
+
+// choices turns ai's plans into simChoices.
+func (s *simBattle) choices(w *World, ai *EnemyAIState, plans []*aiPlan) []*simChoice {
+	index := make(map[*Mob]int, len(s.units))
+	for i, u := range s.units {
+		index[u.mob] = i
+	}
+	choices := make([]*simChoice, 0, searchCandidates)
+	for _, plan := range plans {
+		if len(choices) == searchCandidates {
+			break
+		}
+		c := &simChoice{plan: plan, x: plan.dest.X, y: plan.dest.Y}
+		if plan.act {
+			for _, t := range ai.targetsOf(w, plan.dest, plan.weapon, plan.target) {
+				j, ok := index[t]
+				if !ok || t.Dead() {
+					continue
+				}
+				c.effects = append(c.effects, s.effect(index[ai.self], j, ai.forecast(w, plan.dest, plan.weapon, t)))
+			}
+		}
+		choices = append(choices, c)
+	}
+	return choices
+}
+
+func (s *simBattle) clone() *simBattle {
+	c := *s
+	c.units = append([]simUnit(nil), s.units...)
+	c.rng = rand.New(rand.NewSource(s.rng.Int63()))
+	c.dist = make([]int, len(s.dist))
+	c.queue = nil
+	return &c
+}
+
+// simStep is a node on the path a playout took, and the team it was picked for.
+type simStep struct {
+	node *simNode
+	team int
+}
+
+// search plays out choices for unit self for about budget, and returns the plan that did the best.
+func (s *simBattle) search(self int, choices []*simChoice, budget time.Duration) *aiPlan {
+	team := s.units[self].team
+	deadline := time.Now().Add(budget)
+	var path []simStep
+	for n := 0; n < len(choices) || time.Now().Before(deadline); n++ {
+		c := pickChoice(choices, n)
+		game := s.clone()
+		game.play(self, c)
+		path = append(path[:0], simStep{&c.simNode, team})
+
+		// down the tree until we add something new to it
+		node, expanded := &c.simNode, c.visits == 0
+		turns := 1
+		for ; turns < rolloutTurns && !expanded; turns++ {
+			i := game.next()
+			if i < 0 {
+				break
+			}
+			game.startTurn(i)
+			node, expanded = node.descend(game.actions(i))
+			path = append(path, simStep{node, game.units[i].team})
+			game.do(i, node.act)
+		}
+		game.rollout(rolloutTurns - turns)
+
+		for _, step := range path {
+			step.node.visits++
+			step.node.total += game.value(step.team)
+		}
+	}
+	// go with the one we're most sure about, which is the one we tried the most
+	best := choices[0]
+	for _, c := range choices {
+		if c.visits > best.visits {
+			best = c
+		}
+	}
+	return best.plan
+}
+
+// pickChoice picks which choice to play out next, balancing how well it's done with how little it's been tried.
+func pickChoice(choices []*simChoice, n int) *simChoice {
+	var best *simChoice
+	bestScore := math.Inf(-1)
+	for _, c := range choices {
+		if c.visits == 0 {
+			return c
+		}
+		score := c.ucb(n)
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// ucb is how promising n looks out of parent visits: how well it's done, plus a bonus for being tried less.
+func (n *simNode) ucb(parent int) float64 {
+	return n.total/float64(n.visits) + exploration*math.Sqrt(math.Log(float64(parent))/float64(n.visits))
+}
+
+// descend picks which of actions to follow from n: the first one that's never been tried,
+// otherwise the one that looks the most promising. It returns true if it's a new node.
+func (n *simNode) descend(actions []simAction) (*simNode, bool) {
+	var best *simNode
+	bestScore := math.Inf(-1)
+	for _, act := range actions {
+		child := n.child(act)
+		if child == nil {
+			child = &simNode{act: act}
+			n.children = append(n.children, child)
+			return child, true
+		}
+		if score := child.ucb(n.visits); score > bestScore {
+			best, bestScore = child, score
+		}
+	}
+	return best, false
+}
+
+func (n *simNode) child(act simAction) *simNode {
+	for _, c := range n.children {
+		if c.act == act {
+			return c
+		}
+	}
+	return nil
+}
+
+// play has unit i carry out choice c.
+func (s *simBattle) play(i int, c *simChoice) {
+	u := &s.units[i]
+	moved := u.x != c.x || u.y != c.y
+	u.x, u.y = c.x, c.y
+	if c.plan.act && c.plan.spell {
+		u.mp -= c.plan.weapon.MPCost
+		for k, move := range s.moves[i] {
+			if move.spell && move.name == c.plan.weapon.Name {
+				u.cooldown[k] = move.cooldown + 1
+			}
+		}
+	}
+	for _, fx := range c.effects {
+		s.roll(i, fx)
+	}
+	s.endTurn(i, moved, c.plan.act)
+}
+
+// do has unit i take action a.
+func (s *simBattle) do(i int, a simAction) {
+	u := &s.units[i]
+	x, y := a.dest%s.width, a.dest/s.width
+	moved := x != u.x || y != u.y
+	u.x, u.y = x, y
+	if a.move >= 0 {
+		move := &s.moves[i][a.move]
+		u.mp -= move.mp
+		// +1 because it's counted down at the start of our next turn, like Mob.StartCooldown
+		u.cooldown[a.move] = move.cooldown + 1
+		s.roll(i, move.odds[a.target])
+	}
+	s.endTurn(i, moved, a.move >= 0)
+}
+
+// rollout plays out the battle for up to turns turns, with every unit doing whatever looks best at a glance.
+func (s *simBattle) rollout(turns int) {
+	for t := 0; t < turns; t++ {
+		i := s.next()
+		if i < 0 {
+			return
+		}
+		s.startTurn(i)
+		s.do(i, s.actions(i)[0])
+	}
+}
+
+// next advances CT until someone is up, like World.NextTurn, and returns them.
+// It returns -1 if the battle is over.
+func (s *simBattle) next() int {
+	if s.over() {
+		return -1
+	}
+	for tries := 0; tries < 1000; tries++ {
+		up := -1
+		for i := range s.units {
+			u := &s.units[i]
+			if u.dead() || u.ct < ctForTurn {
+				continue
+			}
+			if up == -1 || u.ct > s.units[up].ct || (u.ct == s.units[up].ct && u.curSpeed() > s.units[up].curSpeed()) {
+				up = i
+			}
+		}
+		if up >= 0 {
+			return up
+		}
+		for i := range s.units {
+			if !s.units[i].dead() {
+				s.units[i].ct += s.units[i].curSpeed()
+			}
+		}
+	}
+	return -1
+}
+
+// over returns true if there's only one team left standing.
+// Like World.shouldWin, it goes by the teams units started on, so charmed units don't count.
+func (s *simBattle) over() bool {
+	team := -1
+	for _, u := range s.units {
+		if u.dead() {
+			continue
+		}
+		if team != -1 && u.home != team {
+			return false
+		}
+		team = u.home
+	}
+	return true
+}
+
+// hostile returns true if unit i wants to hurt unit j. Berserk units want to hurt everyone.
+func (s *simBattle) hostile(i, j int) bool {
+	return i != j && (s.units[i].team != s.units[j].team || s.units[i].has(simBerserk))
+}
+
+// actions lists what unit i could do with its turn, most promising first and at most maxBranch of them:
+// each of its moves on everyone it's worth using on, from as far away as the move allows,
+// and getting closer to the nearest enemy.
+func (s *simBattle) actions(i int) []simAction {
+	type ranked struct {
+		simAction
+		worth float64
+	}
+	u := &s.units[i]
+	start := u.y*s.width + u.x
+	tiles := s.reachable(i)
+	var acts []ranked
+	if u.canAct() {
+		for k, move := range s.moves[i] {
+			if move.spell && (u.has(simSilence) || u.has(simBerserk) || u.mp < move.mp || u.cooldown[k] > 0) {
+				continue
+			}
+			for j := range s.units {
+				worth := s.worth(i, move.odds[j])
+				if s.units[j].dead() || worth <= 0 {
+					continue
+				}
+				dest := start
+				if j != i {
+					// keep as much distance as the move allows
+					e := &s.units[j]
+					dest = -1
+					bestDist := 0
+					for _, tile := range tiles {
+						d := abs(tile%s.width-e.x) + abs(tile/s.width-e.y)
+						if d <= move.reach && d > bestDist {
+							dest, bestDist = tile, d
+						}
+					}
+				}
+				if dest >= 0 {
+					acts = append(acts, ranked{simAction{dest: dest, move: k, target: j}, worth})
+				}
+			}
+		}
+	}
+	// or get closer to whoever's nearest
+	closest, bestDist := start, -1
+	for _, tile := range tiles {
+		for j := range s.units {
+			e := &s.units[j]
+			if e.dead() || !s.hostile(i, j) {
+				continue
+			}
+			if d := abs(tile%s.width-e.x) + abs(tile/s.width-e.y); bestDist == -1 || d < bestDist {
+				closest, bestDist = tile, d
+			}
+		}
+	}
+	acts = append(acts, ranked{simAction{dest: closest, move: -1, target: -1}, 0})
+
+	sort.SliceStable(acts, func(a, b int) bool {
+		return acts[a].worth > acts[b].worth
+	})
+	list := make([]simAction, 0, min(len(acts), maxBranch))
+	for _, act := range acts[:min(len(acts), maxBranch)] {
+		list = append(list, act.simAction)
+	}
+	return list
+}
+
+// worth is a quick guess at how much unit i would like fx to happen, for ranking actions.
+// It's about 1 for taking out a healthy enemy.
+func (s *simBattle) worth(i int, fx simEffect) float64 {
+	if fx.hit == 0 {
+		return 0
+	}
+	t := &s.units[fx.target]
+	hostile := s.hostile(i, fx.target)
+	avg := float64(fx.min+fx.max) / 2
+	worth := 0.0
+	switch {
+	case avg > 0:
+		dmg := math.Min(avg, float64(t.hp)) / float64(t.maxHP)
+		if fx.max >= t.hp {
+			dmg += 0.5
+		}
+		if hostile {
+			worth += dmg
+		} else {
+			worth -= dmg * allyFire
+		}
+	case avg < 0:
+		healed := math.Min(-avg, float64(t.maxHP-t.hp)) / float64(t.maxHP)
+		if hostile {
+			worth -= healed
+		} else {
+			worth += healed
+		}
+	}
+	if fx.status != simNone && !t.has(fx.status) {
+		if helpfulBuffs[simStatuses[fx.status].name] == hostile {
+			worth -= statusWorth
+		} else {
+			worth += statusWorth
+		}
+	}
+	return worth * fx.hit
+}
+
+// startTurn starts unit i's turn like Mob.TakeTurn: statuses count down, heals over time kick in,
+// cooldowns count down, and MP comes back.
+func (s *simBattle) startTurn(i int) {
+	u := &s.units[i]
+	for st := range u.status {
+		life := u.status[st]
+		if life == 0 {
+			continue
+		}
+		info := simStatuses[st]
+		if info.breakChance != 0 && s.rng.Float64() <= info.breakChance {
+			life = 0
+		} else if life > 0 {
+			life--
+		}
+		u.status[st] = life
+		switch {
+		case life == 0 && simStatus(st) == simCharm:
+			u.team = u.home
+		case life != 0 && info.dot[0] < 0:
+			s.hurt(i, s.between(info.dot[0], info.dot[1]))
+		}
+	}
+	for k := range u.cooldown {
+		if u.cooldown[k] > 0 {
+			u.cooldown[k]--
+		}
+	}
+	u.mp = min(u.mp+u.mpRegen, u.maxMP)
+}
+
+// endTurn finishes unit i's turn like Mob.FinishTurn: CT goes down, and damage over time hurts.
+func (s *simBattle) endTurn(i int, moved, acted bool) {
+	u := &s.units[i]
+	u.ct = ctAfterTurn(u.ct, moved, acted)
+	for st, life := range u.status {
+		if info := simStatuses[st]; life != 0 && info.dot[0] > 0 && !u.dead() {
+			s.hurt(i, s.between(info.dot[0], info.dot[1]))
+		}
+	}
+}
+
+// roll rolls the dice for unit i doing fx.
+func (s *simBattle) roll(i int, fx simEffect) {
+	hit := fx.hit
+	if s.units[i].has(simBlind) {
+		hit -= blindPenalty
+	}
+	if s.rng.Float64() >= hit {
+		return
+	}
+	t := &s.units[fx.target]
+	if fx.min != 0 || fx.max != 0 {
+		dmg := s.between(fx.min, fx.max)
+		if dmg > 0 && t.has(simProtect) {
+			dmg = max(dmg-protectDefense, 1)
+		}
+		s.hurt(fx.target, dmg)
+	}
+	if fx.status == simNone || t.dead() {
+		return
+	}
+	// charm and cripple are Unique, the rest replace what's there
+	if (fx.status == simCharm || fx.status == simCripple) && t.has(fx.status) {
+		return
+	}
+	info := simStatuses[fx.status]
+	t.status[fx.status] = s.between(info.min, info.max)
+	if fx.status == simCharm {
+		t.team = s.units[i].team
+	}
+}
+
+// hurt deals dmg to unit i, or heals it if dmg is negative. Getting hurt breaks sleep, like Buff.BreakOnDamage.
+func (s *simBattle) hurt(i int, dmg int) {
+	u := &s.units[i]
+	u.hp = clamp(u.hp-dmg, 0, u.maxHP)
+	if dmg > 0 {
+		u.status[simSleep] = 0
+	}
+}
+
+// between returns a random number from lo to hi, in either order.
+func (s *simBattle) between(lo, hi int) int {
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	return lo + s.rng.Intn(hi-lo+1)
+}
+
+// reachable returns the indexes of the tiles unit i can move to this turn, including where it is now.
+func (s *simBattle) reachable(i int) []int {
+	u := &s.units[i]
+	start := u.y*s.width + u.x
+	if !u.canMove() {
+		return []int{start}
+	}
+	costs := s.walk
+	if u.flying {
+		costs = s.fly
+	}
+	for j := range s.dist {
+		s.dist[j] = -1
+	}
+	for _, o := range s.units {
+		if !o.dead() {
+			s.dist[o.y*s.width+o.x] = -2 // occupied
+		}
+	}
+	s.dist[start] = 0
+	s.queue = append(s.queue[:0], start)
+	tiles := []int{start}
+	for len(s.queue) > 0 {
+		cur := s.queue[0]
+		s.queue = s.queue[1:]
+		x, y := cur%s.width, cur/s.width
+		for _, dir := range neighbors {
+			nx, ny := x+dir[0], y+dir[1]
+			next := ny*s.width + nx
+			if nx < 0 || ny < 0 || nx >= s.width || next >= len(costs) || costs[next] < 0 || s.dist[next] == -2 {
+				continue
+			}
+			cost := s.dist[cur] + costs[next]
+			if cost > u.move || (s.dist[next] >= 0 && s.dist[next] <= cost) {
+				continue
+			}
+			if s.dist[next] == -1 {
+				tiles = append(tiles, next)
+			}
+			s.dist[next] = cost
+			s.queue = append(s.queue, next)
+		}
+	}
+	return tiles
+}
+
+// value is how well the battle's going for team, from 0 (wiped out) to 1 (flawless victory).
+// Units count for the team they started on, since charms wear off.
+func (s *simBattle) value(team int) float64 {
+	score := 0.0
+	for _, u := range s.units {
+		worth := float64(u.hp) / float64(u.maxHP)
+		if !u.dead() {
+			worth += 0.5
+		}
+		if u.home == team {
+			score += worth
+		} else {
+			score -= worth
+		}
+	}
+	return 0.5 + score/(3*float64(len(s.units)))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	r := require.New(t)
+
+	w, _ := newTestWorld(t, "oneroom")
+	w.thinkTime = 50 * time.Millisecond
+
+	bear := spawn(w, 38, 13, &Mob{team: 1, base: Stats{Move: 4, Speed: 5}, weapon: weaponBite})
+	// nearly dead, but dangerous
+	wounded := spawn(w, 42, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4, Speed: 5}, weapon: weaponSwipe})
+	wounded.hp = 2
+
+	bear.ct = ctForTurn
+	ai := &EnemyAIState{self: bear}
+	sim := newSimBattle(w, ai)
+	r.Len(sim.units, 2)
+	choices := sim.choices(w, ai, ai.plans(w))
+	r.Len(choices, searchCandidates)
+
+	self := 0
+	for i, u := range sim.units {
+		if u.mob == bear {
+			self = i
+		}
+	}
+	plan := sim.search(self, choices, w.thinkTime)
+	r.True(plan.act)
+	r.Equal(wounded, plan.target)
+
+	// the real battle is untouched
+	r.Equal(2, wounded.HP())
+	r.Equal(38, bear.Loc().X)
+	total, deepest := 0, 0
+	for _, c := range choices {
+		total += c.visits
+		deepest = max(deepest, depth(&c.simNode))
+	}
+	r.Greater(total, len(choices))
+	// it looked further ahead than its own turn
+	r.Greater(deepest, 1)
+}
+
+func depth(n *simNode) int {
+	d := 0
+	for _, c := range n.children {
+		d = max(d, depth(c))
+	}
+	return d + 1
+}
+
+func TestSimStatus(t *testing.T) {
+	r := require.New(t)
+
+	w, _ := newTestWorld(t, "oneroom")
+	witch := spawn(w, 38, 13, &Mob{team: 1, base: Stats{Move: 4, Speed: 10}, weapon: weaponBite})
+	witch.spells = []Weapon{spellSlow, spellCharm}
+	witch.maxMP = 20
+	witch.mp = 20
+	knight := spawn(w, 40, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4, Speed: 10}, weapon: weaponSwipe})
+	knight.ApplyBuff(w, buffSleep(3), witch)
+	friend := spawn(w, 44, 13, &Mob{team: PlayerTeam, base: Stats{Move: 4, Speed: 10}, weapon: weaponSwipe})
+	w.battle.Teams = []Team{{ID: PlayerTeam, Units: []*Mob{knight, friend}}, {ID: 1, Units: []*Mob{witch}}}
+
+	sim := newSimBattle(w, &EnemyAIState{self: witch})
+	r.Len(sim.units, 3)
+	index := make(map[*Mob]int)
+	for i, u := range sim.units {
+		index[u.mob] = i
+	}
+	me, k, f := index[witch], index[knight], index[friend]
+	r.Len(sim.moves[me], 3)
+
+	// buffs the units already have come along
+	r.Equal(3, sim.units[k].status[simSleep])
+	r.False(sim.units[k].canAct())
+	sim.startTurn(k)
+	r.Equal([]simAction{{dest: 13*sim.width + 40, move: -1, target: -1}}, sim.actions(k))
+	sim.hurt(k, 1)
+	r.True(sim.units[k].canAct())
+
+	// and new ones can be put on
+	slow := simEffect{target: f, hit: 1, status: simSlow}
+	sim.roll(me, slow)
+	r.Equal(5, sim.units[f].curSpeed())
+	sim.units[f].status[simSlow] = 1
+	sim.startTurn(f)
+	r.Equal(10, sim.units[f].curSpeed())
+
+	// charmed units fight for the other side until it wears off
+	sim.roll(me, sim.moves[me][2].odds[k])
+	r.Equal(1, sim.units[k].team)
+	r.Equal(PlayerTeam, sim.units[k].home)
+	sim.units[k].status[simCharm] = 1
+	sim.startTurn(k)
+	r.Equal(PlayerTeam, sim.units[k].team)
+
+	// the real units are untouched
+	r.True(knight.HasBuff("sleep"))
+	r.False(friend.HasBuff("slow"))
+	r.Equal(PlayerTeam, knight.Team())
+}
@@ -20,3 +20,24 @@ func max(a, b int) int {
 	}
 	return b
 }
+
+func sign(a int) int {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	}
+	return 0
+}
+
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
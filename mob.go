@@ -3,8 +3,12 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strings"
 )
 
+// blinkTicks is how often a mob cycles through its BG colors.
+const blinkTicks = 25
+
 type Loc struct {
 	Map     string
 	X, Y, Z int
@@ -30,11 +34,15 @@ type Object interface {
 type Ticker interface {
 	Object
 	Tick(*World, int64)
+	// NextTick returns the next tick this object needs to run at, or -1 if it's idle.
+	NextTick(w *World, now int64) int64
 }
 
 type Collider interface {
 	Object
 	Collides(*World, ID) bool
+	// OnCollide is called when the object with the given ID steps onto this one's tile.
+	// It returns true if that should stop it in its tracks.
 	OnCollide(*World, ID) bool
 }
 
@@ -61,30 +69,47 @@ type Mob struct {
 	spells []Weapon
 	armor  Armor
 
-	hp    int
-	maxHP int
-	mp    int
-	maxMP int
+	hp     int
+	maxHP  int
+	mp     int
+	maxMP  int
+	shield Shield // absorbs damage before hp
 
 	base  Stats
 	stats Stats
 	bgIdx int // index of BG color to show
 
-	moved bool
-	acted bool
+	moved  bool
+	acted  bool
+	flying bool // flyers ignore terrain costs and can cross water etc.
+	facing Direction
+
+	affinities Affinities // elemental resistances, weaknesses, etc.
+	reaction   Reaction
 
 	tauntedBy *Mob
+	charging  *Charge
 	buffs     map[*Buff]struct{}
-	cooldowns map[string]int
+	cooldowns map[string]int // spell name → turns left, counted down at the start of each turn
+
+	personality *Personality // how it behaves under AI control, nil = balanced
+	stance      Stance       // for player units, whether the AI controls it and how
 }
 
 type Stats struct {
 	Move         int // move range
 	Speed        int // how much to increment CT
 	Defense      int // physical defense
+	Jump         int // how many height levels a unit can climb in one step
+	Accuracy     int // bonus to hit (percent)
+	Evasion      int // chance to dodge (percent)
 	MagicDefense int // magical defense
 	CantMove     bool
 	CantAct      bool
+	Perception   int    // range to spot hidden traps, 0 = default
+	Vision       int    // sight radius in fog of war mode, 0 = default
+	Silenced     bool   // can't cast spells
+	Berserk      bool   // controlled by AI, attacks whoever's closest
 	BGs          Colors // glyph BGs to cycle through
 }
 
@@ -95,10 +120,17 @@ func (m *Mob) Reset(w *World) {
 	m.moved = false
 	m.acted = false
 	m.tauntedBy = nil
+	m.charging = nil
+	m.shield = Shield{}
 	m.buffs = make(map[*Buff]struct{})
 	m.cooldowns = make(map[string]int)
 	m.refreshStats(w)
 	m.bgIdx = 0
+	if m.team == PlayerTeam {
+		m.facing = North
+	} else {
+		m.facing = South
+	}
 }
 
 func (m *Mob) Create(w *World) {
@@ -196,9 +228,9 @@ func (m *Mob) TakeTurn(w *World) {
 	}
 
 	m.AddMP(m.Armor().MPRecovery + 1)
+	w.spotTraps(m)
 
-	// TODO: friendly AI
-	if m.Team() != 0 && !w.gameOver {
+	if m.AIControlled(w) && (m.CanAct() || m.CanMove()) && !w.gameOver {
 		w.push <- &EnemyAIState{
 			self: m,
 		}
@@ -214,9 +246,12 @@ func (m *Mob) Speed() int {
 }
 
 func (m *Mob) FinishTurn(w *World, moved, acted bool) {
-	// apply DoTs
 	dead := m.Dead()
 	if !dead {
+		w.endTurnOn(m)
+	}
+	// apply DoTs
+	if !m.Dead() {
 		for buff := range m.buffs {
 			if buff.DoT.IsValid() && buff.DoT.Type != DamageHealing {
 				dmg := m.Damage(w, buff.DoT)
@@ -238,17 +273,63 @@ func (m *Mob) FinishTurn(w *World, moved, acted bool) {
 		)
 	}
 
+	m.ct = ctAfterTurn(m.ct, moved, acted)
+}
+
+// ctAfterTurn returns a unit's CT after it ends its turn.
+// Waiting costs less than moving or acting, so the unit gets its next turn sooner.
+func ctAfterTurn(ct int, moved, acted bool) int {
 	if moved && acted {
-		m.ct -= 100
-		return
+		return ct - 100
 	}
 	if moved || acted {
-		m.ct -= 80
+		return ct - 80
+	}
+	ct -= 60
+	if ct > 60 {
+		ct = 60
+	}
+	return ct
+}
+
+func (m *Mob) Facing() Direction {
+	return m.facing
+}
+
+func (m *Mob) Face(dir Direction) {
+	m.facing = dir
+}
+
+// FaceToward turns this mob to look at loc.
+func (m *Mob) FaceToward(loc Loc) {
+	if loc.X == m.loc.X && loc.Y == m.loc.Y {
 		return
 	}
-	m.ct -= 60
-	if m.ct > 60 {
-		m.ct = 60
+	m.facing = directionTo(m.loc, loc)
+}
+
+// FlankFrom returns which side of this mob an attack from loc would hit.
+func (m *Mob) FlankFrom(loc Loc) Flank {
+	return flankOf(m.loc, m.facing, loc)
+}
+
+// FaceNearestEnemy turns this mob toward the closest living unit on another team.
+func (m *Mob) FaceNearestEnemy(w *World) {
+	var nearest *Mob
+	dist := -1
+	for _, obj := range w.Map(m.loc.Map).Objects {
+		other, ok := obj.(*Mob)
+		if !ok || other.Team() == m.Team() || other.Dead() || !w.Sees(m.Team(), other) {
+			continue
+		}
+		d := abs(other.loc.X-m.loc.X) + abs(other.loc.Y-m.loc.Y)
+		if dist == -1 || d < dist {
+			nearest = other
+			dist = d
+		}
+	}
+	if nearest != nil {
+		m.FaceToward(nearest.Loc())
 	}
 }
 
@@ -257,13 +338,47 @@ func (m *Mob) Collides(_ *World, _ ID) bool {
 }
 
 func (m *Mob) OnCollide(_ *World, _ ID) bool {
-	return true
+	return false
 }
 
 func (m *Mob) Move(loc Loc) {
 	m.loc = loc
 }
 
+// defaultJump is used for mobs without a jump stat.
+const defaultJump = 2
+
+func (m *Mob) Jump() int {
+	if m.stats.Jump == 0 {
+		return defaultJump
+	}
+	return m.stats.Jump
+}
+
+// Vision returns how far this unit can see in fog of war mode.
+func (m *Mob) Vision() int {
+	if m.stats.Vision == 0 {
+		return defaultVision
+	}
+	return m.stats.Vision
+}
+
+func (m *Mob) Perception() int {
+	if m.stats.Perception == 0 {
+		return defaultPerception
+	}
+	return m.stats.Perception
+}
+
+func (m *Mob) Flying() bool {
+	return m.flying
+}
+
+// Tile returns the tile this mob is standing on.
+func (m *Mob) Tile(w *World) *Tile {
+	return w.Map(m.loc.Map).TileAtLoc(m.loc)
+}
+
 func (m *Mob) MoveRange() int {
 	if m.stats.CantMove {
 		return 0
@@ -280,7 +395,7 @@ func (m *Mob) CanAttackFrom(world *World, loc Loc, other *Mob, weapon Weapon) bo
 	myloc := loc
 	otherloc := other.Loc()
 	mymap := world.Map(myloc.Map)
-	if abs(myloc.X-otherloc.X)+abs(myloc.Y-otherloc.Y) <= weapon.Range {
+	if abs(myloc.X-otherloc.X)+abs(myloc.Y-otherloc.Y) <= weapon.RangeFrom(mymap, myloc, otherloc) {
 		_, blocked, _ := mymap.Raycast(myloc, otherloc, weapon.Magic)
 		return !blocked
 	}
@@ -305,10 +420,51 @@ func (m *Mob) Spells() []Weapon {
 	return m.spells
 }
 
+// Spell looks up one of m's spells by name, ignoring case.
+func (m *Mob) Spell(name string) (Weapon, bool) {
+	for _, spell := range m.spells {
+		if strings.EqualFold(spell.Name, name) {
+			return spell, true
+		}
+	}
+	return Weapon{}, false
+}
+
+// Charging returns the spell this mob is charging, or nil.
+func (m *Mob) Charging() *Charge {
+	return m.charging
+}
+
+// Cooldown returns how many more turns until spell can be cast again.
+func (m *Mob) Cooldown(spell Weapon) int {
+	return m.cooldowns[spell.Name]
+}
+
+// StartCooldown puts spell on cooldown after casting it.
+func (m *Mob) StartCooldown(spell Weapon) {
+	if spell.Cooldown <= 0 {
+		return
+	}
+	if m.cooldowns == nil {
+		m.cooldowns = make(map[string]int)
+	}
+	// +1 because it's counted down at the start of our next turn
+	m.cooldowns[spell.Name] = spell.Cooldown + 1
+}
+
+// CanCast returns true if m has enough MP for spell, it's not on cooldown, and m isn't silenced.
+func (m *Mob) CanCast(spell Weapon) bool {
+	return spell.MPCost <= m.MP() && m.Cooldown(spell) == 0 && !m.Silenced()
+}
+
 func (m *Mob) Armor() Armor {
 	return m.armor
 }
 
+func (m *Mob) Affinities() Affinities {
+	return m.affinities
+}
+
 func (m *Mob) Defense() int {
 	return m.Armor().Defense + m.stats.Defense
 }
@@ -351,7 +507,15 @@ func (m *Mob) CanAct() bool {
 }
 
 func (m *Mob) CanMove() bool {
-	return !m.Dead()
+	return !m.stats.CantMove && !m.Dead()
+}
+
+func (m *Mob) Berserk() bool {
+	return m.stats.Berserk
+}
+
+func (m *Mob) Silenced() bool {
+	return m.stats.Silenced
 }
 
 func (m *Mob) Attackable() bool {
@@ -363,22 +527,19 @@ func (m *Mob) Damage(w *World, dmg Damage) int {
 		return 0
 	}
 
-	def := m.Defense()
-	if dmg.Type == DamageMagic {
-		def = m.stats.MagicDefense
-	}
-	hit := dmg.Dice.Roll()
-	if dmg.Type == DamageHealing {
-		hit = -hit
+	hit := m.mitigate(w, dmg, dmg.Roll())
+	hit = m.absorb(w, dmg, hit)
+	m.hp -= hit
+	if hit > 0 && m.charging != nil {
+		m.charging.interrupt(w)
 	}
 	if hit > 0 {
-		hit -= def
-		if hit <= 0 {
-			hit = 1
+		for buff := range m.buffs {
+			if buff.BreakOnDamage {
+				m.RemoveBuff(w, buff)
+			}
 		}
 	}
-
-	m.hp -= hit
 	if m.hp > m.maxHP {
 		m.hp = m.maxHP
 	}
@@ -390,6 +551,34 @@ func (m *Mob) Damage(w *World, dmg Damage) int {
 	return hit
 }
 
+// mitigate returns how much damage a roll of dmg really deals to m after defense.
+// Healing comes back negative.
+func (m *Mob) mitigate(w *World, dmg Damage, roll int) int {
+	if dmg.Type == DamageHealing {
+		return -roll
+	}
+	switch aff := m.Affinities().Of(dmg.Element); aff {
+	case AffinityImmune:
+		return 0
+	case AffinityAbsorb:
+		return -roll
+	case AffinityResist, AffinityWeak:
+		roll = roll * aff.Scale() / 100
+	}
+	def := m.Defense() + m.Tile(w).Defense
+	if dmg.Type == DamageMagic {
+		def = m.stats.MagicDefense
+	}
+	if roll <= 0 {
+		return roll
+	}
+	roll -= def
+	if roll <= 0 {
+		roll = 1
+	}
+	return roll
+}
+
 func (m *Mob) ApplyBuff(w *World, buff *Buff, src *Mob) {
 	if buff.Unique() {
 		for existing := range m.buffs {
@@ -415,6 +604,40 @@ func (m *Mob) ApplyBuff(w *World, buff *Buff, src *Mob) {
 	m.refreshStats(w)
 }
 
+// HasBuff returns true if m has a buff with the given name.
+func (m *Mob) HasBuff(name string) bool {
+	for buff := range m.buffs {
+		if buff.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveBuff takes a buff off m early.
+func (m *Mob) RemoveBuff(w *World, buff *Buff) {
+	if _, ok := m.buffs[buff]; !ok {
+		return
+	}
+	delete(m.buffs, buff)
+	if buff.OnRemove != nil {
+		buff.OnRemove(w, m)
+	}
+	m.refreshStats(w)
+}
+
+// Cleanse removes all of m's harmful buffs (or all beneficial ones, to dispel) and returns how many it removed.
+func (m *Mob) Cleanse(w *World, harmful bool) int {
+	n := 0
+	for buff := range m.buffs {
+		if buff.Harmful == harmful {
+			m.RemoveBuff(w, buff)
+			n++
+		}
+	}
+	return n
+}
+
 func (m *Mob) refreshStats(w *World) {
 	stats := m.base
 	if stats.BGs != nil {
@@ -435,6 +658,9 @@ func (m *Mob) refreshStats(w *World) {
 		m.bgIdx = 0
 	}
 	m.stats = stats
+	if m.charging != nil && !m.CanAct() {
+		m.charging.interrupt(w)
+	}
 }
 
 func (m *Mob) Tick(w *World, tick int64) {
@@ -442,16 +668,31 @@ func (m *Mob) Tick(w *World, tick int64) {
 		m.actions[0](m, w)
 		m.actions = m.actions[1:]
 	}
-	if tick%25 == 0 && len(m.stats.BGs) > 0 {
+	if tick%blinkTicks == 0 && len(m.stats.BGs) > 0 && w.needsBlink() {
 		m.bgIdx = (m.bgIdx + 1) % len(m.stats.BGs)
 	}
 }
 
+func (m *Mob) NextTick(w *World, now int64) int64 {
+	if len(m.actions) > 0 {
+		return now + 1
+	}
+	if len(m.stats.BGs) > 1 && w.needsBlink() {
+		return now - now%blinkTicks + blinkTicks
+	}
+	return -1
+}
+
 func (m *Mob) Enqueue(action func(*Mob, *World)) {
 	m.actions = append(m.actions, action)
 }
 
 func (mob *Mob) StatusLine(short bool) []Glyph {
+	return mob.statusLine(short, mob.Glyph())
+}
+
+// statusLine is StatusLine showing the mob as glyph.
+func (mob *Mob) statusLine(short bool, glyph Glyph) []Glyph {
 	mobname := mob.Name()
 	if mob.class != "" {
 		if short {
@@ -485,22 +726,35 @@ func (mob *Mob) StatusLine(short bool) []Glyph {
 			if i != 0 {
 				buffnames = Concat(buffnames, ", ")
 			}
-			buffnames = Concat(buffnames, GlyphsOf(buffs[i].Name, StyleFG(buffs[i].BG)))
+			buffnames = Concat(buffnames, GlyphsOf(buffs[i].Label(), StyleFG(buffs[i].BG)))
 		}
 	}
 
+	var charging []Glyph
+	if mob.charging != nil {
+		charging = Concat("; casting ", GlyphsOf(mob.charging.spell.Name, StyleBold))
+	}
+
 	return Concat(
-		"[", mob.Glyph(), "] ",
+		"[", glyph, mob.facingArrow(), "] ",
 		mobname,
 		" (HP: ", mob.HPText(),
 		mp,
 		", ", speed, ": ", mob.Speed(),
 		", CT: ", mob.CT(),
 		buffnames,
+		charging,
 		")",
 	)
 }
 
+func (m *Mob) facingArrow() []Glyph {
+	if m.Dead() {
+		return nil
+	}
+	return []Glyph{GlyphOf(m.Facing().Arrow())}
+}
+
 func (m *Mob) HPText() []Glyph {
 	hp, maxhp := m.HP(), m.MaxHP()
 	var hpcolor Color
@@ -512,7 +766,11 @@ func (m *Mob) HPText() []Glyph {
 	case hp <= maxhp/2:
 		hpcolor = ColorOlive
 	}
-	return GlyphsOf(fmt.Sprintf("%d/%d", hp, maxhp), StyleFG(hpcolor))
+	text := GlyphsOf(fmt.Sprintf("%d/%d", hp, maxhp), StyleFG(hpcolor))
+	if m.shield.HP > 0 {
+		text = Concat(text, GlyphsOf(fmt.Sprintf("+%d", m.shield.HP), StyleFG(m.shield.Color())))
+	}
+	return text
 }
 
 var _ Ticker = &Mob{}
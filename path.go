@@ -0,0 +1,271 @@
+package main
+
+import (
+	"container/heap"
+)
+
+// PathMap is the result of flooding a map from a single tile.
+// It holds the cost to reach every tile and the way back to the origin,
+// so any number of destinations can be looked up without searching again.
+type PathMap struct {
+	Map    *Map
+	Origin Loc
+
+	width  int
+	height int
+	cost   []int // -1 = unreachable
+	parent []int // index of previous tile, -1 = none
+}
+
+// Flood runs a Dijkstra flood fill from loc, stopping once the movement cost goes over limit (-1 = no limit).
+// mover is the object that's moving (or nil): it doesn't block itself, and flying mobs ignore terrain.
+func (m *Map) Flood(from Loc, limit int, mover Object) *PathMap {
+	w, h := m.Width(), m.Height()
+	pm := &PathMap{
+		Map:    m,
+		Origin: Loc{Map: m.Name, X: from.X, Y: from.Y},
+		width:  w,
+		height: h,
+		cost:   make([]int, w*h),
+		parent: make([]int, w*h),
+	}
+	for i := range pm.cost {
+		pm.cost[i] = -1
+		pm.parent[i] = -1
+	}
+	if !pm.inBounds(from.X, from.Y) {
+		return pm
+	}
+
+	flying := false
+	jump := -1
+	if mob, ok := mover.(*Mob); ok {
+		flying = mob.Flying()
+		if !flying {
+			jump = mob.Jump()
+		}
+	}
+	costs := m.occupancy(flying, mover)
+	heights := m.heightGrid()
+	start := pm.index(from.X, from.Y)
+	pm.cost[start] = 0
+	queue := &pathQueue{{idx: start}}
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(pathNode)
+		if node.cost > pm.cost[node.idx] {
+			// stale entry, we already found a cheaper way here
+			continue
+		}
+		x, y := node.idx%w, node.idx/w
+		for _, dir := range neighbors {
+			nx, ny := x+dir[0], y+dir[1]
+			if !pm.inBounds(nx, ny) {
+				continue
+			}
+			next := pm.index(nx, ny)
+			if costs[next] < 0 {
+				continue
+			}
+			if jump >= 0 && abs(heights[next]-heights[node.idx]) > jump {
+				continue
+			}
+			cost := node.cost + costs[next]
+			if limit >= 0 && cost > limit {
+				continue
+			}
+			if pm.cost[next] != -1 && pm.cost[next] <= cost {
+				continue
+			}
+			pm.cost[next] = cost
+			pm.parent[next] = node.idx
+			heap.Push(queue, pathNode{idx: next, cost: cost})
+		}
+	}
+	return pm
+}
+
+// Cost returns the movement cost to reach the given tile, or -1 if it's unreachable.
+func (pm *PathMap) Cost(x, y int) int {
+	if !pm.inBounds(x, y) {
+		return -1
+	}
+	return pm.cost[pm.index(x, y)]
+}
+
+// Truncate cuts path off at the last step that costs no more than limit to reach.
+// path must come from this PathMap.
+func (pm *PathMap) Truncate(path []Loc, limit int) []Loc {
+	for i, step := range path {
+		if pm.Cost(step.X, step.Y) > limit {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// Reachable returns true if the given tile can be reached, not counting the origin.
+func (pm *PathMap) Reachable(x, y int) bool {
+	if x == pm.Origin.X && y == pm.Origin.Y {
+		return false
+	}
+	return pm.Cost(x, y) > 0
+}
+
+// PathTo returns the steps from the origin to the given tile, not including the origin.
+// It returns nil if the tile is unreachable.
+func (pm *PathMap) PathTo(x, y int) []Loc {
+	if !pm.Reachable(x, y) {
+		return nil
+	}
+	var path []Loc
+	for idx := pm.index(x, y); idx != -1 && pm.parent[idx] != -1; idx = pm.parent[idx] {
+		path = append(path, Loc{Map: pm.Map.Name, X: idx % pm.width, Y: idx / pm.width})
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// NextTo returns the cheapest path to a tile adjacent to target.
+// If the origin is already adjacent, it returns an empty but non-nil path.
+// It returns nil when no adjacent tile can be reached.
+func (pm *PathMap) NextTo(target Loc) []Loc {
+	best := -1
+	var bestX, bestY int
+	for _, dir := range neighbors {
+		x, y := target.X+dir[0], target.Y+dir[1]
+		if x == pm.Origin.X && y == pm.Origin.Y {
+			return []Loc{}
+		}
+		cost := pm.Cost(x, y)
+		if cost <= 0 {
+			continue
+		}
+		if best == -1 || cost < best {
+			best = cost
+			bestX, bestY = x, y
+		}
+	}
+	if best == -1 {
+		return nil
+	}
+	return pm.PathTo(bestX, bestY)
+}
+
+func (pm *PathMap) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < pm.width && y < pm.height
+}
+
+func (pm *PathMap) index(x, y int) int {
+	return y*pm.width + x
+}
+
+// neighbors are the directions units can move in.
+var neighbors = [4][2]int{
+	{0, -1},
+	{1, 0},
+	{0, 1},
+	{-1, 0},
+}
+
+// costGrid returns the movement cost of every tile on this map, with -1 for impassable tiles.
+// It's built once per kind of mover and kept until invalidatePaths is called.
+func (m *Map) costGrid(flying bool) []int {
+	cached := &m.walkGrid
+	if flying {
+		cached = &m.flyGrid
+	}
+	if *cached != nil {
+		return *cached
+	}
+	w, h := m.Width(), m.Height()
+	grid := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			tile := m.TileAt(x, y)
+			switch {
+			case !tile.Passable(flying):
+				grid[y*w+x] = -1
+			case flying:
+				grid[y*w+x] = 1
+			default:
+				grid[y*w+x] = tile.MoveCost()
+			}
+		}
+	}
+	*cached = grid
+	return grid
+}
+
+// heightGrid returns the height of every tile on this map.
+func (m *Map) heightGrid() []int {
+	if m.heights != nil {
+		return m.heights
+	}
+	w, h := m.Width(), m.Height()
+	m.heights = make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.heights[y*w+x] = m.TileAt(x, y).Height
+		}
+	}
+	return m.heights
+}
+
+// invalidatePaths throws away the cached cost grids.
+// Call it whenever a tile's collision or terrain changes.
+func (m *Map) invalidatePaths() {
+	m.version++
+	m.walkGrid = nil
+	m.flyGrid = nil
+	m.heights = nil
+}
+
+// occupancy returns the cost grid with mobs and other colliders on top.
+func (m *Map) occupancy(flying bool, ignore ...Object) []int {
+	grid := m.costGrid(flying)
+	costs := make([]int, len(grid))
+	copy(costs, grid)
+	w := m.Width()
+loop:
+	for _, obj := range m.Objects {
+		for _, ig := range ignore {
+			if ig == obj {
+				continue loop
+			}
+		}
+		col, ok := obj.(Collider)
+		if !ok || !col.Collides(nil, 0) {
+			continue
+		}
+		loc := obj.Loc()
+		if loc.X < 0 || loc.Y < 0 || loc.X >= w || loc.Y*w+loc.X >= len(costs) {
+			continue
+		}
+		costs[loc.Y*w+loc.X] = -1
+	}
+	return costs
+}
+
+type pathNode struct {
+	idx  int
+	cost int
+}
+
+// pathQueue is a min-heap of path nodes, for container/heap.
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	node := old[n-1]
+	*q = old[:n-1]
+	return node
+}
+
+var _ heap.Interface = (*pathQueue)(nil)
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlood(t *testing.T) {
+	r := require.New(t)
+
+	m, err := loadMap("oneroom")
+	r.NoError(err)
+	from := m.SpawnPoints[PlayerTeam][0]
+
+	paths := m.Flood(from, -1, nil)
+	r.False(paths.Reachable(from.X, from.Y))
+	r.Equal(0, paths.Cost(from.X, from.Y))
+
+	// one step in each direction
+	r.Equal(1, paths.Cost(from.X+1, from.Y))
+	path := paths.PathTo(from.X+1, from.Y)
+	r.Equal([]Loc{{Map: m.Name, X: from.X + 1, Y: from.Y}}, path)
+
+	// paths are contiguous and as long as their cost
+	to := m.SpawnPoints[AITeam][0]
+	path = paths.PathTo(to.X, to.Y)
+	r.Len(path, paths.Cost(to.X, to.Y))
+	prev := from
+	for _, step := range path {
+		r.Equal(1, abs(step.X-prev.X)+abs(step.Y-prev.Y))
+		prev = step
+	}
+	r.Equal(to.X, prev.X)
+	r.Equal(to.Y, prev.Y)
+
+	// walls are never reachable
+	r.False(paths.Reachable(0, 0))
+	r.Nil(paths.PathTo(0, 0))
+
+	// limits cut off the flood
+	limited := m.Flood(from, 2, nil)
+	r.True(limited.Reachable(from.X+2, from.Y))
+	r.False(limited.Reachable(from.X+3, from.Y))
+}
+
+func TestFloodOccupancy(t *testing.T) {
+	r := require.New(t)
+
+	m, err := loadMap("oneroom")
+	r.NoError(err)
+	from := m.SpawnPoints[PlayerTeam][0]
+
+	blocker := &Mob{id: 1, hp: 1, loc: Loc{Map: m.Name, X: from.X + 1, Y: from.Y}}
+	m.Add(blocker)
+	defer m.Reset()
+
+	paths := m.Flood(from, -1, nil)
+	r.False(paths.Reachable(blocker.loc.X, blocker.loc.Y))
+	r.Equal(4, paths.Cost(from.X+2, from.Y)) // around it
+
+	// adjacent already
+	r.NotNil(paths.NextTo(blocker.Loc()))
+	r.Empty(paths.NextTo(blocker.Loc()))
+
+	// ignored objects don't block
+	paths = m.Flood(from, -1, blocker)
+	r.True(paths.Reachable(blocker.loc.X, blocker.loc.Y))
+}
+
+func TestFloodTerrain(t *testing.T) {
+	r := require.New(t)
+
+	m, err := loadMap("islandfort")
+	r.NoError(err)
+
+	// find some water next to the shore
+	var shore, water Loc
+	for y := 0; y < m.Height() && water.Map == ""; y++ {
+		for x := 1; x < m.Width(); x++ {
+			if m.TileAt(x, y).FlyOnly && m.TileAt(x-1, y).Passable(false) {
+				shore = Loc{Map: m.Name, X: x - 1, Y: y}
+				water = Loc{Map: m.Name, X: x, Y: y}
+				break
+			}
+		}
+	}
+	r.NotEmpty(water.Map)
+
+	walker := &Mob{hp: 1}
+	r.False(m.Flood(shore, -1, walker).Reachable(water.X, water.Y))
+	flyer := &Mob{hp: 1, flying: true}
+	r.True(m.Flood(shore, -1, flyer).Reachable(water.X, water.Y))
+
+	// mud costs extra
+	var mud Loc
+	for y := 0; y < m.Height() && mud.Map == ""; y++ {
+		for x := 1; x < m.Width(); x++ {
+			if m.TileAt(x, y).MoveCost() == 2 && m.TileAt(x-1, y).MoveCost() == 1 {
+				mud = Loc{Map: m.Name, X: x, Y: y}
+				break
+			}
+		}
+	}
+	r.NotEmpty(mud.Map)
+	r.Equal(2, m.Flood(Loc{Map: m.Name, X: mud.X - 1, Y: mud.Y}, -1, walker).Cost(mud.X, mud.Y))
+}
+
+func BenchmarkFlood(b *testing.B) {
+	m, err := loadMap("islandfort")
+	if err != nil {
+		b.Fatal(err)
+	}
+	from := m.SpawnPoints[PlayerTeam][0]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Flood(from, -1, nil)
+	}
+}
+
+func BenchmarkFloodLimited(b *testing.B) {
+	m, err := loadMap("islandfort")
+	if err != nil {
+		b.Fatal(err)
+	}
+	from := m.SpawnPoints[PlayerTeam][0]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Flood(from, 6, nil)
+	}
+}
+
+func BenchmarkPathTo(b *testing.B) {
+	m, err := loadMap("islandfort")
+	if err != nil {
+		b.Fatal(err)
+	}
+	from := m.SpawnPoints[PlayerTeam][0]
+	to := m.SpawnPoints[AITeam][0]
+	paths := m.Flood(from, -1, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		paths.PathTo(to.X, to.Y)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/guregu/dicey"
+)
+
+// ReactionType is what a unit does automatically when it's attacked.
+type ReactionType int
+
+const (
+	ReactionNone        ReactionType = iota
+	ReactionCounter                  // attack back when hit in melee range
+	ReactionDodge                    // chance to dodge attacks that would hit
+	ReactionFirstStrike              // hit melee attackers before they can swing
+	ReactionHeal                     // recover HP when hit
+	ReactionMPOnHit                  // recover MP when hit
+)
+
+// Reaction is a unit's reaction ability.
+type Reaction struct {
+	Type   ReactionType
+	Chance int // percent chance to trigger, 0 = always
+	Amount int // HP or MP recovered
+}
+
+var reactionNames = map[ReactionType]string{
+	ReactionCounter:     "counter",
+	ReactionDodge:       "auto-dodge",
+	ReactionFirstStrike: "first strike",
+	ReactionHeal:        "regenerate",
+	ReactionMPOnHit:     "mana shield",
+}
+
+func (r Reaction) String() string {
+	return reactionNames[r.Type]
+}
+
+func (r Reaction) glyph() Glyph {
+	switch r.Type {
+	case ReactionCounter, ReactionFirstStrike:
+		return Glyph{Rune: '!', SGR: SGR{FG: ColorBrightYellow, Bold: true}}
+	case ReactionDodge:
+		return Glyph{Rune: '~', SGR: SGR{FG: ColorWhite}}
+	case ReactionHeal:
+		return Glyph{Rune: '+', SGR: SGR{FG: ColorBrightGreen}}
+	case ReactionMPOnHit:
+		return Glyph{Rune: '+', SGR: SGR{FG: ColorBrightBlue}}
+	}
+	return GlyphOf('?')
+}
+
+func (m *Mob) Reaction() Reaction {
+	return m.reaction
+}
+
+// reacts returns true if m's reaction of the given type goes off against an attack from source.
+// Reactions only happen against enemies' damaging attacks, and never in response to other reactions.
+func (m *Mob) reacts(typ ReactionType, source *Mob, weapon Weapon) bool {
+	if m.reaction.Type != typ || !m.CanAct() || source.Team() == m.Team() || !weapon.Damage.Harmful() {
+		return false
+	}
+	if typ == ReactionCounter || typ == ReactionFirstStrike {
+		sloc := source.Loc()
+		if source.Dead() || abs(sloc.X-m.loc.X)+abs(sloc.Y-m.loc.Y) != 1 {
+			return false
+		}
+	}
+	return m.reaction.Chance == 0 || rand.Intn(100) < m.reaction.Chance
+}
+
+// showReaction flashes a reaction's glyph over the unit.
+func (w *World) showReaction(m *Mob) {
+	loc := m.Loc()
+	loc.Z = 999
+	w.Add(&Effect{
+		loc:   loc,
+		glyph: m.reaction.glyph(),
+		life:  15,
+	})
+}
+
+// strikeBack has m attack source as a counter or first strike.
+func (w *World) strikeBack(m, source *Mob) {
+	w.showReaction(m)
+	w.Broadcast(
+		m.NameColored(),
+		" reacts with ",
+		GlyphsOf(m.reaction.String(), StyleBold),
+		"!",
+	)
+	w.attack(source, m, m.Weapon(), false)
+}
+
+// recoverOnHit runs heal-when-hit and MP-on-hit reactions after m took dmg.
+func (w *World) recoverOnHit(m, source *Mob, weapon Weapon, dmg int) {
+	if dmg <= 0 || m.Dead() {
+		return
+	}
+	switch {
+	case m.reacts(ReactionHeal, source, weapon):
+		healed := m.Damage(w, Damage{Dice: dicey.MustParse(strconv.Itoa(m.reaction.Amount)), Type: DamageHealing})
+		w.showReaction(m)
+		w.Broadcast(
+			m.NameColored(),
+			" reacts with ",
+			GlyphsOf(m.reaction.String(), StyleBold),
+			" and recovers ",
+			ColorDamage(healed),
+			" HP.",
+		)
+	case m.reacts(ReactionMPOnHit, source, weapon):
+		m.AddMP(m.reaction.Amount)
+		w.showReaction(m)
+		w.Broadcast(
+			m.NameColored(),
+			" reacts with ",
+			GlyphsOf(m.reaction.String(), StyleBold),
+			" and recovers ",
+			GlyphsOf(strconv.Itoa(m.reaction.Amount), StyleFG(ColorBrightBlue)),
+			" MP.",
+		)
+	}
+}
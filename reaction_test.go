@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterDoesNotLoop(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := m.SpawnPoints[PlayerTeam][0]
+
+	weapon := Weapon{Name: "zap", Damage: Damage{Dice: dicey.MustParse("1"), Type: DamageMagic}, Range: 1, Magic: true}
+	counter := Reaction{Type: ReactionCounter}
+	a := spawn(w, at.X, at.Y, &Mob{maxHP: 10, weapon: weapon, reaction: counter})
+	b := spawn(w, at.X, at.Y-1, &Mob{team: AITeam, maxHP: 10, weapon: weapon, reaction: counter})
+
+	w.Attack(b, a, weapon)
+	r.Equal(9, b.HP())
+	r.Equal(9, a.HP()) // countered once, and a doesn't counter the counter
+
+	// no reactions to friendly fire
+	b.team = a.team
+	w.Attack(b, a, weapon)
+	r.Equal(8, b.HP())
+	r.Equal(9, a.HP())
+}
+
+func TestNoCounterToDebuffs(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := m.SpawnPoints[PlayerTeam][0]
+
+	caster := spawn(w, at.X, at.Y, &Mob{maxHP: 10, maxMP: 20})
+	knight := spawn(w, at.X, at.Y-1, &Mob{team: AITeam, maxHP: 10, weapon: weaponSword, reaction: Reaction{Type: ReactionCounter}})
+
+	w.Attack(knight, caster, spellSlow)
+	r.Equal(10, caster.HP())
+}
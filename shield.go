@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/guregu/dicey"
+)
+
+// ShieldType restricts which kinds of damage a shield soaks up.
+type ShieldType int
+
+const (
+	ShieldAll      ShieldType = iota
+	ShieldPhysical            // only blocks normal attacks
+	ShieldMagic               // only blocks magic
+)
+
+// Shield is a pool of extra HP that absorbs damage before the unit's own HP.
+type Shield struct {
+	Name  string
+	Type  ShieldType
+	HP    int
+	MaxHP int
+	// OnBreak is called when the shield is used up.
+	OnBreak func(w *World, m *Mob)
+}
+
+// Blocks returns true if this shield absorbs dmg.
+func (s Shield) Blocks(dmg Damage) bool {
+	if s.HP <= 0 {
+		return false
+	}
+	switch s.Type {
+	case ShieldPhysical:
+		return dmg.Type == DamageNormal
+	case ShieldMagic:
+		return dmg.Type == DamageMagic
+	}
+	return dmg.Type == DamageNormal || dmg.Type == DamageMagic
+}
+
+// Absorb returns how much of hit the shield would soak up.
+func (s Shield) Absorb(dmg Damage, hit int) int {
+	if hit <= 0 || !s.Blocks(dmg) {
+		return 0
+	}
+	return min(hit, s.HP)
+}
+
+func (s Shield) Color() Color {
+	switch s.Type {
+	case ShieldPhysical:
+		return Color256(180)
+	case ShieldMagic:
+		return Color256(141)
+	}
+	return Color256(117)
+}
+
+func (m *Mob) Shield() Shield {
+	return m.shield
+}
+
+// AddShield puts a shield on m, replacing any weaker one.
+func (m *Mob) AddShield(w *World, shield Shield) {
+	if m.shield.HP > shield.HP {
+		w.BroadcastString("It wasn't effective.")
+		return
+	}
+	if shield.MaxHP == 0 {
+		shield.MaxHP = shield.HP
+	}
+	m.shield = shield
+	w.Broadcast(
+		m.NameColored(),
+		" is protected by a ",
+		GlyphsOf(shield.Name, StyleFG(shield.Color())),
+		" (",
+		GlyphsOf(fmt.Sprintf("+%d", shield.HP), StyleFG(shield.Color())),
+		").",
+	)
+}
+
+// absorb runs hit through m's shield and returns the damage that gets through.
+func (m *Mob) absorb(w *World, dmg Damage, hit int) int {
+	soaked := m.shield.Absorb(dmg, hit)
+	if soaked == 0 {
+		return hit
+	}
+	m.shield.HP -= soaked
+	if m.shield.HP > 0 {
+		return hit - soaked
+	}
+
+	shield := m.shield
+	m.shield = Shield{}
+	loc := m.Loc()
+	loc.Z = 999
+	w.Add(&Effect{
+		loc:   loc,
+		glyph: Glyph{Rune: '✶', SGR: SGR{FG: shield.Color(), Bold: true}},
+		life:  15,
+	})
+	w.Broadcast(m.NameColored(), "'s ", shield.Name, " broke!")
+	if shield.OnBreak != nil {
+		shield.OnBreak(w, m)
+	}
+	return hit - soaked
+}
+
+// blockedText describes how much damage a shield absorbed, for attack messages.
+func blockedText(blocked int) []Glyph {
+	if blocked <= 0 {
+		return nil
+	}
+	return GlyphsOf(fmt.Sprintf(" (%d blocked)", blocked))
+}
+
+// shieldOnHit returns an OnHit func that gives the target a shield with HP rolled from dice.
+func shieldOnHit(shield Shield, dice string) func(w *World, source *Mob, target *Mob) {
+	d := dicey.MustParse(dice)
+	return func(w *World, source *Mob, target *Mob) {
+		if target.Dead() {
+			return
+		}
+		shield.HP = d.Roll()
+		target.AddShield(w, shield)
+	}
+}
+
+// spellBarrier blocks physical attacks.
+var spellBarrier = Weapon{
+	Name:      "barrier",
+	Damage:    Damage{Type: DamageNone},
+	Range:     6,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '◘', SGR: SGR{FG: Color256(180)}},
+	MPCost:    6,
+	Buffs:     []string{"barrier"},
+	OnHit:     shieldOnHit(Shield{Name: "barrier", Type: ShieldPhysical}, "2d4+6"),
+}
+
+// spellSanctuary blocks everything, but costs a lot.
+var spellSanctuary = Weapon{
+	Name:       "sanctuary",
+	Damage:     Damage{Type: DamageNone},
+	Range:      5,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '◘', SGR: SGR{FG: Color256(117)}},
+	MPCost:     14,
+	Cooldown:   3,
+	Buffs:      []string{"sanctuary"},
+	OnHit:      shieldOnHit(Shield{Name: "sanctuary", Type: ShieldAll}, "1d4+4"),
+}
+
+// spellWard blocks magic, and gives back some MP when it breaks.
+var spellWard = Weapon{
+	Name:       "ward",
+	Damage:     Damage{Type: DamageNone},
+	Range:      5,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '◘', SGR: SGR{FG: Color256(141)}},
+	MPCost:     8,
+	Buffs:      []string{"ward"},
+	OnHit: shieldOnHit(Shield{
+		Name: "ward",
+		Type: ShieldMagic,
+		OnBreak: func(w *World, m *Mob) {
+			if m.MaxMP() == 0 || m.Dead() {
+				return
+			}
+			m.AddMP(3)
+			w.Broadcast(
+				m.NameColored(),
+				" soaks up the ward and recovers ",
+				GlyphsOf("3", StyleFG(ColorBrightBlue)),
+				" MP.",
+			)
+		},
+	}, "2d4+4"),
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShield(t *testing.T) {
+	r := require.New(t)
+
+	w, level := newTestWorld(t, "oneroom")
+	at := level.SpawnPoints[PlayerTeam][0]
+	m := spawn(w, at.X, at.Y, &Mob{})
+
+	broke := false
+	m.AddShield(w, Shield{Name: "test", Type: ShieldPhysical, HP: 5, OnBreak: func(*World, *Mob) {
+		broke = true
+	}})
+
+	// magic goes right through
+	m.Damage(w, Damage{Dice: dicey.MustParse("3"), Type: DamageMagic})
+	r.Equal(17, m.HP())
+	r.Equal(5, m.Shield().HP)
+
+	// physical hits the shield first
+	r.Equal(0, m.Damage(w, Damage{Dice: dicey.MustParse("3")}))
+	r.Equal(17, m.HP())
+	r.Equal(2, m.Shield().HP)
+
+	r.Equal(2, m.Damage(w, Damage{Dice: dicey.MustParse("4")}))
+	r.Equal(15, m.HP())
+	r.Zero(m.Shield().HP)
+	r.True(broke)
+}
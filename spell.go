@@ -10,7 +10,7 @@ import (
 
 var spellFireball = Weapon{
 	Name:       "fireball",
-	Damage:     Damage{Dice: dicey.MustParse("2d3"), Type: DamageMagic},
+	Damage:     Damage{Dice: dicey.MustParse("2d3"), Type: DamageMagic, Element: ElementFire},
 	Range:      6,
 	Targeting:  TargetingFree,
 	Magic:      true,
@@ -23,7 +23,7 @@ var spellFireball = Weapon{
 
 var spellFireball2 = Weapon{
 	Name:       "fireball ii",
-	Damage:     Damage{Dice: dicey.MustParse("6d4"), Type: DamageMagic},
+	Damage:     Damage{Dice: dicey.MustParse("6d4"), Type: DamageMagic, Element: ElementFire},
 	Range:      6,
 	Targeting:  TargetingFree,
 	Magic:      true,
@@ -32,11 +32,12 @@ var spellFireball2 = Weapon{
 	HitGlyph:   &Glyph{Rune: 'X', SGR: SGR{BG: ColorBrightYellow, FG: ColorRed}},
 	MPCost:     5,
 	projectile: projectileFunc(Glyph{Rune: 'o', SGR: SGR{FG: ColorRed}}),
+	Charge:     20,
 }
 
 var spellMeteor = Weapon{
 	Name:       "meteor",
-	Damage:     Damage{Dice: dicey.MustParse("4d3"), Type: DamageMagic},
+	Damage:     Damage{Dice: dicey.MustParse("4d3"), Type: DamageMagic, Element: ElementFire},
 	Range:      7,
 	Targeting:  TargetingFree,
 	Magic:      true,
@@ -45,11 +46,13 @@ var spellMeteor = Weapon{
 	HitGlyph:   &Glyph{Rune: 'X', SGR: SGR{BG: ColorBrightYellow, FG: ColorRed}},
 	MPCost:     8,
 	projectile: projectileFunc(Glyph{Rune: 'O', SGR: SGR{FG: ColorRed}}),
+	Charge:     12,
+	Cooldown:   2,
 }
 
 var spellBolt = Weapon{
 	Name:       "bolt",
-	Damage:     Damage{Dice: dicey.MustParse("5d5+2"), Type: DamageMagic},
+	Damage:     Damage{Dice: dicey.MustParse("5d5+2"), Type: DamageMagic, Element: ElementLightning},
 	Range:      6,
 	Targeting:  TargetingFree,
 	Magic:      true,
@@ -57,6 +60,7 @@ var spellBolt = Weapon{
 	HitboxSize: 1,
 	HitGlyph:   &Glyph{Rune: 'X', SGR: SGR{BG: ColorBrightYellow, FG: ColorRed}},
 	MPCost:     10,
+	Cooldown:   1,
 }
 
 var spellHeal = Weapon{
@@ -91,7 +95,7 @@ var spellHeal2 = Weapon{
 
 var spellSmite = Weapon{
 	Name:      "smite",
-	Damage:    Damage{Dice: dicey.MustParse("2d10+1"), Type: DamageMagic},
+	Damage:    Damage{Dice: dicey.MustParse("2d10+1"), Type: DamageMagic, Element: ElementHoly},
 	Range:     5,
 	Targeting: TargetingFree,
 	Magic:     true,
@@ -102,7 +106,7 @@ var spellSmite = Weapon{
 
 var spellSmite2 = Weapon{
 	Name:      "smite ii",
-	Damage:    Damage{Dice: dicey.MustParse("3d10+2"), Type: DamageMagic},
+	Damage:    Damage{Dice: dicey.MustParse("3d10+2"), Type: DamageMagic, Element: ElementHoly},
 	Range:     5,
 	Targeting: TargetingFree,
 	Magic:     true,
@@ -124,6 +128,8 @@ var spellGloria = Weapon{
 	HitboxSize: 3,
 	HitGlyph:   &Glyph{Rune: '✚', SGR: SGR{FG: ColorBrightGreen}},
 	MPCost:     10,
+	Charge:     15,
+	Cooldown:   2,
 }
 
 var spellRenew = Weapon{
@@ -135,6 +141,7 @@ var spellRenew = Weapon{
 	MPCost:    5,
 	Hitbox:    HitboxSingle,
 	HitGlyph:  &Glyph{Rune: '✚', SGR: SGR{FG: ColorBrightGreen}},
+	Buffs:     []string{"renew"},
 	OnHit: func(w *World, source *Mob, target *Mob) {
 		life := rand.Intn(3) + 4
 		buff := newBuff("renew", NotUnique, life, 0)
@@ -161,6 +168,7 @@ var spellTaunt = Weapon{
 	Magic:     true,
 	Hitbox:    HitboxSingle,
 	HitGlyph:  &Glyph{Rune: '!', SGR: SGR{FG: ColorDarkRed}},
+	Buffs:     []string{"taunt"},
 	OnHit: func(w *World, source *Mob, target *Mob) {
 		if source.Team() == target.Team() {
 			w.Broadcast(
@@ -172,6 +180,7 @@ var spellTaunt = Weapon{
 			return
 		}
 		buff := newBuff("taunt", UniqueReplace, -1, 0)
+		buff.Harmful = true
 		buff.BG = Color256(166)
 		buff.OnApply = func(w *World, m *Mob, src *Mob) {
 			m.tauntedBy = src
@@ -192,6 +201,7 @@ var spellTaunt = Weapon{
 		}
 		target.ApplyBuff(w, buff, source)
 	},
+	Cooldown: 2,
 }
 
 var spellCharge = Weapon{
@@ -203,13 +213,13 @@ var spellCharge = Weapon{
 	Hitbox:    HitboxSingle,
 	// HitGlyph:  &Glyph{Rune: '!', SGR: SGR{FG: ColorDarkRed}},
 	OnHit: func(w *World, source *Mob, target *Mob) {
-		m := w.Map(source.Loc().Map)
-		path := m.FindPathNextTo(source, target)
-		switch len(path) {
-		case 0:
+		loc := source.Loc()
+		path := w.Map(loc.Map).Flood(loc, -1, source).NextTo(target.Loc())
+		switch {
+		case path == nil:
 			w.Broadcast("Something is in the way.")
 			return
-		case 1:
+		case len(path) <= 1:
 			w.Broadcast("Too close.")
 			return
 		}
@@ -256,6 +266,7 @@ var spellCharge = Weapon{
 		// }
 		// target.ApplyBuff(w, buff, source)
 	},
+	Cooldown: 2,
 }
 
 var spellCripple = Weapon{
@@ -267,9 +278,11 @@ var spellCripple = Weapon{
 	MPCost: 5,
 	Hitbox: HitboxSingle,
 	// HitGlyph:   &Glyph{Rune: 'x', SGR: SGR{FG: ColorDarkRed}},
+	Buffs: []string{"cripple"},
 	OnHit: func(w *World, source *Mob, target *Mob) {
 		life := rand.Intn(6) + 2
 		buff := newBuff("cripple", Unique, life, 0.1)
+		buff.Harmful = true
 		buff.BG = Color256(237)
 		buff.OnApply = func(w *World, m *Mob, src *Mob) {
 			w.Broadcast(
@@ -300,25 +313,7 @@ var spellPoisonShot = Weapon{
 	MPCost: 5,
 	Hitbox: HitboxSingle,
 	// HitGlyph:   &Glyph{Rune: 'x', SGR: SGR{FG: ColorDarkRed}},
-	OnHit: func(w *World, source *Mob, target *Mob) {
-		life := rand.Intn(3) + 4
-		buff := newBuff("poison", NotUnique, life, 0.1)
-		buff.BG = ColorDiarrhea
-		buff.DoT = Damage{
-			Dice: dicey.MustParse("1d4+1"),
-		}
-		buff.OnApply = func(w *World, m *Mob, src *Mob) {
-			w.Broadcast(
-				m.NameColored(),
-				" is ",
-				GlyphsOf("poisoned", StyleFG(ColorDiarrhea)),
-				"!",
-			)
-		}
-		buff.OnRemove = func(w *World, m *Mob) {
-			// w.Broadcast(m.Name() + " ")
-		}
-		target.ApplyBuff(w, buff, source)
-	},
+	Buffs:      []string{"poison"},
+	OnHit:      statusOnHit(buffPoison, 4, 6),
 	projectile: projectileFunc(Glyph{Rune: '*', SGR: SGR{FG: ColorDiarrhea}}),
 }
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/guregu/dicey"
+)
+
+// Status effects. Each returns a fresh buff lasting the given number of turns.
+
+func buffPoison(life int) *Buff {
+	buff := newBuff("poison", NotUnique, life, 0.1)
+	buff.Harmful = true
+	buff.BG = ColorDiarrhea
+	buff.DoT = Damage{
+		Dice:    dicey.MustParse("1d4+1"),
+		Element: ElementPoison,
+	}
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(
+			m.NameColored(),
+			" is ",
+			GlyphsOf("poisoned", StyleFG(ColorDiarrhea)),
+			"!",
+		)
+	}
+	return buff
+}
+
+func buffStun(life int) *Buff {
+	buff := newBuff("stun", UniqueReplace, life, 0)
+	buff.BG = Color256(220)
+	buff.Harmful = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " is ", GlyphsOf("stunned", StyleFG(buff.BG)), "!")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.CantAct = true
+	}
+	return buff
+}
+
+func buffSleep(life int) *Buff {
+	buff := newBuff("sleep", UniqueReplace, life, 0)
+	buff.BG = Color256(61)
+	buff.Harmful = true
+	buff.BreakOnDamage = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " fell ", GlyphsOf("asleep", StyleFG(buff.BG)), "!")
+	}
+	buff.OnRemove = func(w *World, m *Mob) {
+		w.Broadcast(m.NameColored(), " woke up.")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.CantAct = true
+		stats.CantMove = true
+		stats.Evasion = 0
+	}
+	return buff
+}
+
+func buffSilence(life int) *Buff {
+	buff := newBuff("silence", UniqueReplace, life, 0)
+	buff.BG = Color256(96)
+	buff.Harmful = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " is ", GlyphsOf("silenced", StyleFG(buff.BG)), "!")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Silenced = true
+	}
+	return buff
+}
+
+func buffBlind(life int) *Buff {
+	buff := newBuff("blind", UniqueReplace, life, 0)
+	buff.BG = Color256(240)
+	buff.Harmful = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " is ", GlyphsOf("blinded", StyleFG(buff.BG)), "!")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Accuracy -= 40
+		stats.Vision = 1
+	}
+	return buff
+}
+
+func buffHaste(life int) *Buff {
+	buff := newBuff("haste", UniqueReplace, life, 0)
+	buff.BG = Color256(45)
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " speeds up!")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Speed += max(1, stats.Speed/2)
+	}
+	return buff
+}
+
+func buffSlow(life int) *Buff {
+	buff := newBuff("slow", UniqueReplace, life, 0)
+	buff.BG = Color256(94)
+	buff.Harmful = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " slows down!")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Speed = max(1, stats.Speed/2)
+	}
+	return buff
+}
+
+func buffBerserk(life int) *Buff {
+	buff := newBuff("berserk", UniqueReplace, life, 0)
+	buff.BG = Color256(160)
+	buff.Harmful = true
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " goes ", GlyphsOf("berserk", StyleFG(buff.BG)), "!")
+	}
+	buff.OnRemove = func(w *World, m *Mob) {
+		w.Broadcast(m.NameColored(), " calms down.")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Berserk = true
+	}
+	return buff
+}
+
+// buffCharm makes a unit fight for the other side until it wears off.
+func buffCharm(life int) *Buff {
+	buff := newBuff("charm", Unique, life, 0)
+	buff.BG = Color256(205)
+	buff.Harmful = true
+	var team int
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		team = m.team
+		m.team = src.Team()
+		w.Broadcast(m.NameColored(), " is ", GlyphsOf("charmed", StyleFG(buff.BG)), " by ", src.NameColored(), "!")
+	}
+	buff.OnRemove = func(w *World, m *Mob) {
+		m.team = team
+		w.Broadcast(m.NameColored(), " came to their senses.")
+	}
+	return buff
+}
+
+func buffRegen(life int) *Buff {
+	buff := newBuff("regen", UniqueReplace, life, 0)
+	buff.BG = Color256(34)
+	buff.DoT = Damage{
+		Dice: dicey.MustParse("1d3+2"),
+		Type: DamageHealing,
+	}
+	return buff
+}
+
+func buffProtect(life int) *Buff {
+	buff := newBuff("protect", UniqueReplace, life, 0)
+	buff.BG = Color256(31)
+	buff.OnApply = func(w *World, m *Mob, src *Mob) {
+		w.Broadcast(m.NameColored(), " is ", GlyphsOf("protected", StyleFG(buff.BG)), ".")
+	}
+	buff.Affect = func(w *World, m *Mob, stats *Stats) {
+		stats.Defense += 2
+		stats.MagicDefense += 2
+	}
+	return buff
+}
+
+// statusOnHit returns an OnHit func that applies a status lasting between min and max turns.
+func statusOnHit(status func(life int) *Buff, min, max int) func(w *World, source *Mob, target *Mob) {
+	return func(w *World, source *Mob, target *Mob) {
+		target.ApplyBuff(w, status(min+rand.Intn(max-min+1)), source)
+	}
+}
+
+var spellSleep = Weapon{
+	Name:      "sleep",
+	Damage:    Damage{Type: DamageNone},
+	Range:     5,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: 'z', SGR: SGR{FG: Color256(61)}},
+	MPCost:    6,
+	Cooldown:  2,
+	Buffs:     []string{"sleep"},
+	OnHit:     statusOnHit(buffSleep, 2, 3),
+}
+
+var spellSilence = Weapon{
+	Name:      "silence",
+	Damage:    Damage{Type: DamageNone},
+	Range:     5,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '…', SGR: SGR{FG: Color256(96)}},
+	MPCost:    5,
+	Buffs:     []string{"silence"},
+	OnHit:     statusOnHit(buffSilence, 2, 4),
+}
+
+var spellBlind = Weapon{
+	Name:       "blind",
+	Damage:     Damage{Type: DamageNone},
+	Range:      5,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '●', SGR: SGR{FG: Color256(240)}},
+	MPCost:     6,
+	Buffs:      []string{"blind"},
+	OnHit:      statusOnHit(buffBlind, 2, 4),
+}
+
+var spellHaste = Weapon{
+	Name:      "haste",
+	Damage:    Damage{Type: DamageNone},
+	Range:     5,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '»', SGR: SGR{FG: Color256(45)}},
+	MPCost:    8,
+	Cooldown:  2,
+	Buffs:     []string{"haste"},
+	OnHit:     statusOnHit(buffHaste, 3, 4),
+}
+
+var spellSlow = Weapon{
+	Name:      "slow",
+	Damage:    Damage{Type: DamageNone},
+	Range:     5,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '«', SGR: SGR{FG: Color256(94)}},
+	MPCost:    6,
+	Buffs:     []string{"slow"},
+	OnHit:     statusOnHit(buffSlow, 3, 4),
+}
+
+var spellBerserk = Weapon{
+	Name:      "frenzy",
+	Damage:    Damage{Type: DamageNone},
+	Range:     5,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '!', SGR: SGR{FG: Color256(160)}},
+	MPCost:    8,
+	Cooldown:  3,
+	Buffs:     []string{"berserk"},
+	OnHit:     statusOnHit(buffBerserk, 2, 3),
+}
+
+var spellCharm = Weapon{
+	Name:      "charm",
+	Damage:    Damage{Type: DamageNone},
+	Range:     4,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '♥', SGR: SGR{FG: Color256(205)}},
+	MPCost:    12,
+	Cooldown:  4,
+	Buffs:     []string{"charm"},
+	OnHit:     statusOnHit(buffCharm, 1, 2),
+}
+
+var spellRegen = Weapon{
+	Name:       "regen",
+	Damage:     Damage{Type: DamageNone},
+	Range:      6,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '✚', SGR: SGR{FG: Color256(34)}},
+	MPCost:     8,
+	Buffs:      []string{"regen"},
+	OnHit:      statusOnHit(buffRegen, 3, 4),
+}
+
+var spellProtect = Weapon{
+	Name:      "protect",
+	Damage:    Damage{Type: DamageNone},
+	Range:     6,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '◊', SGR: SGR{FG: Color256(31)}},
+	MPCost:    5,
+	Buffs:     []string{"protect"},
+	OnHit:     statusOnHit(buffProtect, 3, 5),
+}
+
+var spellCleanse = Weapon{
+	Name:       "cleanse",
+	Damage:     Damage{Type: DamageNone},
+	Range:      6,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	HitGlyph:   &Glyph{Rune: '✧', SGR: SGR{FG: ColorWhite}},
+	MPCost:     6,
+	OnHit: func(w *World, source *Mob, target *Mob) {
+		if target.Cleanse(w, true) > 0 {
+			w.Broadcast(source.NameColored(), " cleansed ", target.NameColored(), ".")
+		}
+	},
+}
+
+var spellDispel = Weapon{
+	Name:      "dispel",
+	Damage:    Damage{Type: DamageNone},
+	Range:     6,
+	Targeting: TargetingFree,
+	Magic:     true,
+	Hitbox:    HitboxSingle,
+	HitGlyph:  &Glyph{Rune: '✧', SGR: SGR{FG: Color256(96)}},
+	MPCost:    6,
+	OnHit: func(w *World, source *Mob, target *Mob) {
+		if target.Cleanse(w, false) > 0 {
+			w.Broadcast(source.NameColored(), " dispelled ", target.NameColored(), "'s magic.")
+		} else {
+			w.BroadcastString("It wasn't effective.")
+		}
+	},
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/guregu/dicey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	r := require.New(t)
+
+	w, level := newTestWorld(t, "oneroom")
+	at := level.SpawnPoints[PlayerTeam][0]
+	m := spawn(w, at.X, at.Y, &Mob{maxMP: 10, base: Stats{Speed: 10}})
+
+	m.ApplyBuff(w, buffSleep(3), m)
+	m.ApplyBuff(w, buffSilence(3), m)
+	m.ApplyBuff(w, buffHaste(3), m)
+	r.False(m.CanAct())
+	r.False(m.CanMove())
+	r.True(m.Silenced())
+	r.Equal(15, m.Speed())
+
+	// sleep breaks when hit
+	m.Damage(w, Damage{Dice: dicey.MustParse("1")})
+	r.True(m.CanAct())
+	r.True(m.CanMove())
+
+	// cleanse takes off the bad stuff only
+	r.Equal(1, m.Cleanse(w, true))
+	r.False(m.Silenced())
+	r.Equal(15, m.Speed())
+
+	// dispel takes the rest
+	r.Equal(1, m.Cleanse(w, false))
+	r.Equal(10, m.Speed())
+}
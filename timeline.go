@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sort"
+)
+
+// TimelineEntry is one turn in the predicted turn order.
+type TimelineEntry struct {
+	Turner   Turner
+	Expiring []string // buffs that wear off at the start of this turn
+}
+
+type simTurner struct {
+	Turner
+	ct    int
+	speed int
+	turns int
+}
+
+// Timeline predicts the next n turns by simulating TurnTick, after the unit that's up
+// ends its turn having moved and/or acted. Everyone else is assumed to move and act.
+func (w *World) Timeline(n int, moved, acted bool) []TimelineEntry {
+	var sim []*simTurner
+	for _, t := range w.waitlist {
+		if m, ok := t.(*Mob); ok && m.Dead() {
+			continue
+		}
+		st := &simTurner{Turner: t, ct: t.CT(), speed: t.Speed()}
+		if t == w.up {
+			if _, ok := t.(*Charge); ok {
+				// going off right now
+				continue
+			}
+			st.ct = ctAfterTurn(st.ct, moved, acted)
+		}
+		sim = append(sim, st)
+	}
+
+	var entries []TimelineEntry
+	// bail out eventually if nobody's gaining any CT
+	for tick := 0; len(entries) < n && len(sim) > 0 && tick < n*ctForTurn; tick++ {
+		for _, st := range sim {
+			st.ct += st.speed
+		}
+		sort.SliceStable(sim, func(i, j int) bool {
+			t0, t1 := sim[i], sim[j]
+			if t0.ct == t1.ct {
+				if t0.speed == t1.speed {
+					return t0.ID() < t1.ID()
+				}
+				return t0.speed > t1.speed
+			}
+			return t0.ct > t1.ct
+		})
+		top := sim[0]
+		if top.ct < ctForTurn {
+			continue
+		}
+		top.turns++
+		entry := TimelineEntry{Turner: top.Turner}
+		if m, ok := top.Turner.(*Mob); ok {
+			entry.Expiring = m.expiringBuffs(top.turns)
+			top.ct = ctAfterTurn(top.ct, true, true)
+		} else {
+			// charges only go off once
+			sim = sim[1:]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// NextTurnIn returns how many turns from now the unit that's up will get to go again,
+// if it ends its turn having moved and/or acted. It returns -1 if that's too far off to tell.
+func (w *World) NextTurnIn(moved, acted bool) int {
+	limit := len(w.waitlist) * 3
+	for i, entry := range w.Timeline(limit, moved, acted) {
+		if entry.Turner == w.up {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// expiringBuffs returns the names of buffs that will wear off at the start of m's nth turn from now.
+func (m *Mob) expiringBuffs(n int) []string {
+	var names []string
+	for buff := range m.buffs {
+		if buff.Life == n {
+			names = append(names, buff.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
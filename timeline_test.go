@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeline(t *testing.T) {
+	r := require.New(t)
+
+	fast := &Mob{id: 1, hp: 1, stats: Stats{Speed: 10}, ct: 100}
+	slow := &Mob{id: 2, hp: 1, stats: Stats{Speed: 5}}
+	w := &World{waitlist: []Turner{fast, slow}, up: fast}
+
+	order := w.Timeline(4, true, true)
+	r.Len(order, 4)
+	// fast goes twice as often
+	r.Equal(fast, order[0].Turner)
+	r.Equal(fast, order[1].Turner)
+	r.Equal(slow, order[2].Turner)
+
+	// waiting gets you your next turn sooner
+	r.Equal(1, w.NextTurnIn(false, false))
+	r.True(w.NextTurnIn(true, true) >= w.NextTurnIn(false, false))
+
+	// buffs wearing off
+	buff := newBuff("test", Unique, 2, 0)
+	slow.buffs = map[*Buff]struct{}{buff: {}}
+	var expires int
+	for i, entry := range w.Timeline(8, true, true) {
+		if len(entry.Expiring) > 0 {
+			r.Equal(slow, entry.Turner)
+			r.Equal([]string{"test"}, entry.Expiring)
+			expires = i
+		}
+	}
+	r.NotZero(expires)
+}
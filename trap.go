@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/guregu/dicey"
+)
+
+// TrapKind describes what a trap does. Maps refer to them by name, see trapKinds.
+type TrapKind struct {
+	Name    string
+	Glyph   Glyph
+	Enter   bool // goes off when a unit steps on it
+	EndTurn bool // goes off when a unit ends its turn on it
+	Stops   bool // stops units that step on it in their tracks
+	Flyers  bool // also affects flying units
+	Uses    int  // times it can go off before it's gone, 0 = forever
+	Harmful bool // hurts whoever sets it off
+
+	Effect func(w *World, t *Trap, m *Mob)
+}
+
+// Trap is a map object that does something to units that step on it.
+type Trap struct {
+	id     ID
+	kind   *TrapKind
+	loc    Loc
+	hidden bool
+	team   int    // units on this team know where it is and won't set it off; -1 = nobody
+	group  string // pressure plates set off every trap in their group
+	uses   int
+}
+
+// MetaTrap places a trap on a map, from the map's JSON.
+type MetaTrap struct {
+	Kind   string
+	At     [2]int
+	Hidden bool   // hidden until spotted or set off
+	Group  string // for pressure plates
+}
+
+const defaultPerception = 1
+
+var trapKinds = map[string]*TrapKind{
+	"spikes": {
+		Name:    "spike trap",
+		Glyph:   Glyph{Rune: '^', SGR: SGR{FG: Color256(250)}},
+		Enter:   true,
+		Stops:   true,
+		Harmful: true,
+		Effect:  trapDamage(Damage{Dice: dicey.MustParse("2d4"), Element: ElementPierce}),
+	},
+	"fire": {
+		Name:    "fire",
+		Glyph:   Glyph{Rune: '^', SGR: SGR{FG: Color256(202), Bold: true}},
+		Enter:   true,
+		EndTurn: true,
+		Flyers:  true,
+		Harmful: true,
+		Effect:  trapDamage(Damage{Dice: dicey.MustParse("1d6"), Element: ElementFire}),
+	},
+	"swamp": {
+		Name:    "poison swamp",
+		Glyph:   Glyph{Rune: '≈', SGR: SGR{FG: ColorDiarrhea}},
+		Enter:   true,
+		EndTurn: true,
+		Harmful: true,
+		Effect: func(w *World, t *Trap, m *Mob) {
+			if !m.HasBuff("poison") {
+				m.ApplyBuff(w, buffPoison(3), nil)
+			}
+		},
+	},
+	"spring": {
+		Name:    "healing spring",
+		Glyph:   Glyph{Rune: '~', SGR: SGR{FG: Color256(45), Bold: true}},
+		EndTurn: true,
+		Effect: func(w *World, t *Trap, m *Mob) {
+			healed := m.Damage(w, Damage{Dice: dicey.MustParse("1d4+3"), Type: DamageHealing})
+			w.Broadcast(m.NameColored(), " recovered ", ColorDamage(healed), " HP from the ", t.Name(), ".")
+		},
+	},
+	"plate": {
+		Name:  "pressure plate",
+		Glyph: Glyph{Rune: '=', SGR: SGR{FG: Color256(244)}},
+		Enter: true,
+		Effect: func(w *World, t *Trap, m *Mob) {
+			w.BroadcastString("Click!")
+			w.triggerGroup(t)
+		},
+	},
+	"darts": {
+		// only goes off from pressure plates
+		Name:    "dart trap",
+		Glyph:   Glyph{Rune: '^', SGR: SGR{FG: Color256(137)}},
+		Harmful: true,
+		Effect:  trapDamage(Damage{Dice: dicey.MustParse("1d6+1"), Element: ElementPierce}),
+	},
+	"caltrops": {
+		Name:    "caltrops",
+		Glyph:   Glyph{Rune: '∴', SGR: SGR{FG: Color256(250)}},
+		Enter:   true,
+		Stops:   true,
+		Uses:    1,
+		Harmful: true,
+		Effect:  trapDamage(Damage{Dice: dicey.MustParse("1d4+1"), Element: ElementPierce}),
+	},
+}
+
+// trapDamage returns a trap effect that deals dmg.
+func trapDamage(dmg Damage) func(w *World, t *Trap, m *Mob) {
+	return func(w *World, t *Trap, m *Mob) {
+		hit := m.Damage(w, dmg)
+		w.Broadcast(m.NameColored(), " took ", ColorDamage(hit), " damage from the ", t.Name(), ".")
+	}
+}
+
+// PlaceTrap puts a new trap of the given kind at loc.
+// Unknown kinds are logged and skipped, returning nil.
+func (w *World) PlaceTrap(kind string, loc Loc, team int, hidden bool) *Trap {
+	k, ok := trapKinds[kind]
+	if !ok {
+		log.Printf("unknown trap kind: %q", kind)
+		return nil
+	}
+	loc.Z = 0
+	t := &Trap{
+		kind:   k,
+		loc:    loc,
+		hidden: hidden,
+		team:   team,
+		uses:   k.Uses,
+	}
+	w.Add(t)
+	return t
+}
+
+// checkTraps makes sure every trap in a map's metadata is a kind we know.
+func checkTraps(traps []MetaTrap) error {
+	for _, meta := range traps {
+		if _, ok := trapKinds[meta.Kind]; !ok {
+			return fmt.Errorf("unknown trap kind: %q", meta.Kind)
+		}
+	}
+	return nil
+}
+
+// placeTraps sets up the traps from m's metadata.
+func (w *World) placeTraps(m *Map) {
+	for _, meta := range m.Meta.Traps {
+		if t := w.PlaceTrap(meta.Kind, Loc{Map: m.Name, X: meta.At[0], Y: meta.At[1]}, -1, meta.Hidden); t != nil {
+			t.group = meta.Group
+		}
+	}
+}
+
+// TrapAt returns the trap at loc, or nil if there isn't one.
+func (m *Map) TrapAt(loc Loc) *Trap {
+	for _, obj := range m.TileAtLoc(loc).Objects {
+		if t, ok := obj.(*Trap); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// collide lets everything on m's tile know that it stepped there.
+// It returns true if something there stopped m in its tracks.
+func (w *World) collide(m *Mob) bool {
+	stop := false
+	// traps can go away when they're triggered
+	for _, obj := range m.Tile(w).List() {
+		if col, ok := obj.(Collider); ok && obj != Object(m) {
+			if col.OnCollide(w, m.ID()) {
+				stop = true
+			}
+		}
+	}
+	return stop || m.Dead()
+}
+
+// endTurnOn sets off the traps that go off when m ends its turn on them.
+func (w *World) endTurnOn(m *Mob) {
+	for _, obj := range m.Tile(w).List() {
+		if t, ok := obj.(*Trap); ok && t.kind.EndTurn && t.affects(m) {
+			t.trigger(w, m)
+		}
+	}
+}
+
+// spotTraps reveals the hidden traps m can see from where it's standing.
+func (w *World) spotTraps(m *Mob) {
+	loc := m.Loc()
+	for _, obj := range w.Map(loc.Map).Objects {
+		t, ok := obj.(*Trap)
+		if !ok || !t.hidden || t.team == m.Team() {
+			continue
+		}
+		if abs(t.loc.X-loc.X)+abs(t.loc.Y-loc.Y) > m.Perception() {
+			continue
+		}
+		t.hidden = false
+		w.Broadcast(m.NameColored(), " spotted a ", GlyphsOf(t.Name(), StyleFG(t.kind.Glyph.FG)), "!")
+	}
+}
+
+// triggerGroup sets off every other trap in plate's group.
+func (w *World) triggerGroup(plate *Trap) {
+	if plate.group == "" {
+		return
+	}
+	m := w.Map(plate.loc.Map)
+	for _, obj := range m.Objects {
+		t, ok := obj.(*Trap)
+		if !ok || t == plate || t.group != plate.group {
+			continue
+		}
+		t.hidden = false
+		for _, other := range m.TileAtLoc(t.loc).List() {
+			if mob, ok := other.(*Mob); ok && !mob.Dead() {
+				t.trigger(w, mob)
+			}
+		}
+	}
+}
+
+// affects returns true if m can set this trap off.
+func (t *Trap) affects(m *Mob) bool {
+	if m.Dead() || (m.Flying() && !t.kind.Flyers) {
+		return false
+	}
+	return t.team < 0 || t.team != m.Team()
+}
+
+// trigger runs the trap's effect on m.
+func (t *Trap) trigger(w *World, m *Mob) {
+	if t.hidden {
+		t.hidden = false
+		w.Broadcast(m.NameColored(), " set off a hidden ", GlyphsOf(t.Name(), StyleFG(t.kind.Glyph.FG)), "!")
+	}
+	if t.kind.Effect != nil {
+		t.kind.Effect(w, t, m)
+	}
+	if t.uses > 0 {
+		t.uses--
+		if t.uses == 0 {
+			w.Delete(t.id)
+		}
+	}
+}
+
+func (t *Trap) Create(w *World) {
+	t.id = w.NextID()
+	w.Map(t.loc.Map).Add(t)
+}
+
+func (t *Trap) ID() ID {
+	return t.id
+}
+
+func (t *Trap) Name() string {
+	return t.kind.Name
+}
+
+func (t *Trap) Glyph() Glyph {
+	return t.kind.Glyph
+}
+
+func (t *Trap) Loc() Loc {
+	return t.loc
+}
+
+func (t *Trap) Move(loc Loc) {
+	t.loc = loc
+}
+
+// Hidden returns true if nobody has spotted this trap yet.
+func (t *Trap) Hidden() bool {
+	return t.hidden
+}
+
+// Harmful returns true if this trap hurts whoever sets it off.
+func (t *Trap) Harmful() bool {
+	return t.kind.Harmful
+}
+
+// Collides returns false: traps don't get in the way, that's the point.
+func (t *Trap) Collides(*World, ID) bool {
+	return false
+}
+
+func (t *Trap) OnCollide(w *World, id ID) bool {
+	m, ok := w.objects[id].(*Mob)
+	if !ok || !t.kind.Enter || !t.affects(m) {
+		return false
+	}
+	t.trigger(w, m)
+	w.checkDied(m)
+	return t.kind.Stops
+}
+
+// placeCaltrops scatters caltrops on an empty tile.
+func placeCaltrops(w *World, source *Mob, at Loc) {
+	m := w.Map(at.Map)
+	tile := m.TileAtLoc(at)
+	if !tile.IsValid() || !tile.Passable(false) || m.TrapAt(at) != nil {
+		return
+	}
+	w.PlaceTrap("caltrops", at, source.Team(), false)
+}
+
+var spellCaltrops = Weapon{
+	Name:       "caltrop shot",
+	Damage:     Damage{Type: DamageNone},
+	Range:      5,
+	Targeting:  TargetingFree,
+	Magic:      true,
+	Hitbox:     HitboxCross,
+	HitboxSize: 1,
+	MPCost:     4,
+	Cooldown:   1,
+	Place:      placeCaltrops,
+	projectile: projectileFunc(Glyph{Rune: '∴', SGR: SGR{FG: Color256(250)}}),
+}
+
+var (
+	_ Collider = (*Trap)(nil)
+)
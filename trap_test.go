@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraps(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	at := func(x, y int) Loc {
+		return Loc{Map: m.Name, X: x, Y: y}
+	}
+
+	mob := spawn(w, 40, 10, &Mob{maxHP: 50, team: PlayerTeam})
+
+	spikes := w.PlaceTrap("spikes", at(40, 8), -1, true)
+	caltrops := w.PlaceTrap("caltrops", at(41, 10), PlayerTeam, false)
+	r.Nil(m.TileAt(40, 8).Top(), "hidden traps don't show up")
+	r.Equal(Object(caltrops), m.TileAt(41, 10).Top())
+
+	// walking over hidden spikes stops you on them
+	move := &MoveState{Obj: mob, Path: []Loc{at(40, 9), at(40, 8), at(40, 7)}}
+	for !move.Run(w) {
+	}
+	r.Equal(at(40, 8), Loc{Map: m.Name, X: mob.Loc().X, Y: mob.Loc().Y})
+	r.Less(mob.HP(), 50)
+	r.False(spikes.Hidden())
+
+	// your own team's caltrops don't hurt
+	hp := mob.HP()
+	move = &MoveState{Obj: mob, Path: []Loc{at(40, 9), at(40, 10), at(41, 10), at(42, 10)}}
+	for !move.Run(w) {
+	}
+	r.Equal(42, mob.Loc().X)
+	r.Equal(hp, mob.HP())
+
+	// but they do hurt the enemy, once
+	mob.team = 1
+	move = &MoveState{Obj: mob, Path: []Loc{at(41, 10), at(40, 10)}}
+	for !move.Run(w) {
+	}
+	r.Equal(41, mob.Loc().X)
+	r.Less(mob.HP(), hp)
+	r.Nil(m.TrapAt(at(41, 10)))
+
+	// maps with traps we don't know about don't load
+	r.NoError(checkTraps([]MetaTrap{{Kind: "spikes"}, {Kind: "caltrops"}}))
+	r.Error(checkTraps([]MetaTrap{{Kind: "boulder"}}))
+	r.Nil(w.PlaceTrap("boulder", at(42, 10), -1, false))
+}
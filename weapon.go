@@ -1,6 +1,8 @@
 package main
 
 import (
+	"math/rand"
+
 	"github.com/guregu/dicey"
 )
 
@@ -10,6 +12,9 @@ type Weapon struct {
 	Range     int
 	Targeting TargetingType
 	Value     int
+	Accuracy  int // bonus to hit (percent)
+	Crit      int // bonus crit chance (percent)
+	CritMult  int // percent of damage on a crit, 0 = default
 
 	// spells
 	Magic      bool // ignore walls etc
@@ -17,21 +22,43 @@ type Weapon struct {
 	HitboxSize int
 	MPCost     int
 	HitGlyph   *Glyph
-	Cooldown   int
+	Cooldown   int // turns to wait before casting again
+	Charge     int // CT gained each tick while charging, 0 = cast instantly
 
 	OnHit      func(w *World, caster *Mob, target *Mob)
+	Buffs      []string                            // names of the buffs and effects OnHit applies, for forecasts
+	Place      func(w *World, source *Mob, at Loc) // called for every tile in the hitbox, so these can target empty tiles
 	projectile func() Object
 }
 
 type Damage struct {
-	Dice dicey.Dice
-	Type DamageType
+	Dice    dicey.Dice
+	Type    DamageType
+	Element Element
+	Scale   int // percent of the rolled damage to deal, 0 = 100%
 }
 
 func (d Damage) IsValid() bool {
 	return d.Dice.Max() != 0
 }
 
+// Harmful returns true if this actually hurts, as opposed to healing or only carrying a status effect.
+func (d Damage) Harmful() bool {
+	return d.IsValid() && (d.Type == DamageNormal || d.Type == DamageMagic)
+}
+
+// Roll rolls the dice and applies Scale.
+func (d Damage) Roll() int {
+	return d.scaled(d.Dice.Roll())
+}
+
+func (d Damage) scaled(n int) int {
+	if d.Scale == 0 {
+		return n
+	}
+	return n * d.Scale / 100
+}
+
 type DamageType int
 
 const (
@@ -56,6 +83,18 @@ const (
 	HitboxBlob
 )
 
+// RangeFrom returns this weapon's range when attacking from one tile to another.
+// Projectiles fly further when shot downhill: +1 range for every 2 levels of height.
+func (w Weapon) RangeFrom(m *Map, from, to Loc) int {
+	r := w.Range
+	if w.projectile != nil && !w.Magic {
+		if diff := m.TileAtLoc(from).Height - m.TileAtLoc(to).Height; diff > 0 {
+			r += diff / 2
+		}
+	}
+	return r
+}
+
 func (w Weapon) RollDamage() int {
 	if !w.Damage.IsValid() {
 		return 0
@@ -65,117 +104,140 @@ func (w Weapon) RollDamage() int {
 
 var weaponShortsword = Weapon{
 	Name:   "shortsword",
-	Damage: Damage{Dice: dicey.MustParse("2d3+2")},
+	Damage: Damage{Dice: dicey.MustParse("2d3+2"), Element: ElementSlash},
 	Range:  1,
 }
 
 var weaponSword = Weapon{
 	Name:   "sword",
-	Damage: Damage{Dice: dicey.MustParse("2d4+3")},
+	Damage: Damage{Dice: dicey.MustParse("2d4+3"), Element: ElementSlash},
 	Range:  1,
 	Value:  1,
 }
 
 var weaponGreatsword = Weapon{
-	Name:   "greatsword",
-	Damage: Damage{Dice: dicey.MustParse("3d5+4")},
-	Range:  1,
-	Value:  2,
+	Name:     "greatsword",
+	Damage:   Damage{Dice: dicey.MustParse("3d5+4"), Element: ElementSlash},
+	Range:    1,
+	Value:    2,
+	Accuracy: -5,
+	CritMult: 200,
 }
 
 var weaponExcaLUEbur = Weapon{
 	Name:   "excaLUEbur",
-	Damage: Damage{Dice: dicey.MustParse("3d6+4")},
+	Damage: Damage{Dice: dicey.MustParse("3d6+4"), Element: ElementSlash},
 	Range:  1,
 	Value:  2,
+	Crit:   10,
 }
 
 var weaponFist = Weapon{
 	Name:   "fist",
-	Damage: Damage{Dice: dicey.MustParse("1d3")},
+	Damage: Damage{Dice: dicey.MustParse("1d3"), Element: ElementBlunt},
 	Range:  1,
 }
 
 var weaponYetiFist = Weapon{
 	Name:   "yetifist",
-	Damage: Damage{Dice: dicey.MustParse("3d3")},
+	Damage: Damage{Dice: dicey.MustParse("3d3"), Element: ElementBlunt},
 	Range:  1,
+	Buffs:  []string{"knockback 1"},
+	OnHit:  knockbackOnHit(1),
+}
+
+var weaponKusarigama = Weapon{
+	Name:       "kusarigama",
+	Damage:     Damage{Dice: dicey.MustParse("1d6"), Element: ElementSlash},
+	Range:      3,
+	Targeting:  TargetingFree,
+	Buffs:      []string{"pull 2"},
+	OnHit:      pullOnHit(2),
+	projectile: projectileFunc(GlyphOf('~')),
 }
 
 var weaponBite = Weapon{
 	Name:   "bite",
-	Damage: Damage{Dice: dicey.MustParse("1d3+1")},
+	Damage: Damage{Dice: dicey.MustParse("1d3+1"), Element: ElementPierce},
 	Range:  1,
 }
 
 var weaponSnowFoxBite = Weapon{
 	Name:   "snow fox bite",
-	Damage: Damage{Dice: dicey.MustParse("2d3+4")},
+	Damage: Damage{Dice: dicey.MustParse("2d3+4"), Element: ElementPierce},
 	Range:  1,
 }
 
 var weaponScratch = Weapon{
 	Name:   "scratch",
-	Damage: Damage{Dice: dicey.MustParse("2d2")},
+	Damage: Damage{Dice: dicey.MustParse("2d2"), Element: ElementSlash},
 	Range:  1,
 }
 
 var weaponLick = Weapon{
 	Name:   "lick",
-	Damage: Damage{Dice: dicey.MustParse("1d2")},
+	Damage: Damage{Dice: dicey.MustParse("1d2"), Element: ElementBlunt},
 	Range:  1,
 }
 
 var weaponPeck = Weapon{
 	Name:   "peck",
-	Damage: Damage{Dice: dicey.MustParse("1d3")},
+	Damage: Damage{Dice: dicey.MustParse("1d3"), Element: ElementPierce},
 	Range:  1,
 }
 
 var weaponSwipe = Weapon{
 	Name:   "swipe",
-	Damage: Damage{Dice: dicey.MustParse("3d4+1")},
+	Damage: Damage{Dice: dicey.MustParse("3d4+1"), Element: ElementSlash},
 	Range:  1,
 }
 
 var weaponShank = Weapon{
 	Name:   "shank",
-	Damage: Damage{Dice: dicey.MustParse("1d5+1")},
+	Damage: Damage{Dice: dicey.MustParse("1d5+1"), Element: ElementPierce},
 	Range:  1,
+	Crit:   10,
 }
 
 var weaponFirebreathing = Weapon{
 	Name:       "firebreathing",
-	Damage:     Damage{Dice: dicey.MustParse("2d8+4")},
+	Damage:     Damage{Dice: dicey.MustParse("2d8+4"), Element: ElementFire},
 	Range:      3,
 	projectile: projectileFunc(Glyph{Rune: '#', SGR: SGR{FG: ColorBrightRed}}),
 }
 
 var weaponPick = Weapon{
-	Name:   "mattock",
-	Damage: Damage{Dice: dicey.MustParse("2d6+3")},
-	Range:  1,
+	Name:     "mattock",
+	Damage:   Damage{Dice: dicey.MustParse("2d6+3"), Element: ElementPierce},
+	Range:    1,
+	Accuracy: -10,
 }
 
 var weaponSpear = Weapon{
 	Name:   "spear",
-	Damage: Damage{Dice: dicey.MustParse("2d8")},
+	Damage: Damage{Dice: dicey.MustParse("2d8"), Element: ElementPierce},
 	Range:  2,
 }
 
 var weaponKick = Weapon{
 	Name:   "kick",
-	Damage: Damage{Dice: dicey.MustParse("3d6")},
+	Damage: Damage{Dice: dicey.MustParse("3d6"), Element: ElementBlunt},
 }
 
 var weaponCrush = Weapon{
 	Name:   "kick",
-	Damage: Damage{Dice: dicey.MustParse("2d10")},
+	Damage: Damage{Dice: dicey.MustParse("2d10"), Element: ElementBlunt},
+	Buffs:  []string{"stun"},
+	OnHit: func(w *World, source *Mob, target *Mob) {
+		if !target.Dead() && rand.Intn(100) < 30 {
+			target.ApplyBuff(w, buffStun(1), source)
+		}
+	},
 }
 
 var weaponBow = Weapon{
 	Name:       "bow",
-	Damage:     Damage{Dice: dicey.MustParse("2d2+1")},
+	Damage:     Damage{Dice: dicey.MustParse("2d2+1"), Element: ElementPierce},
 	Range:      6,
 	Targeting:  TargetingFree,
 	projectile: projectileFunc(GlyphOf('*')),
@@ -183,32 +245,35 @@ var weaponBow = Weapon{
 
 var weaponLongbow = Weapon{
 	Name:       "longbow",
-	Damage:     Damage{Dice: dicey.MustParse("2d5+2")},
+	Damage:     Damage{Dice: dicey.MustParse("2d5+2"), Element: ElementPierce},
 	Range:      7,
 	Targeting:  TargetingFree,
 	projectile: projectileFunc(GlyphOf('*')),
 	Value:      1,
+	Crit:       5,
 }
 
 var weaponCrossbow = Weapon{
 	Name:       "crossbow",
-	Damage:     Damage{Dice: dicey.MustParse("3d4+4")},
+	Damage:     Damage{Dice: dicey.MustParse("3d4+4"), Element: ElementPierce},
 	Range:      6,
 	Targeting:  TargetingFree,
 	projectile: projectileFunc(GlyphOf('*')),
 	Value:      2,
+	Accuracy:   10,
 }
 
 var weaponStaff = Weapon{
 	Name:   "staff",
-	Damage: Damage{Dice: dicey.MustParse("1d4")},
+	Damage: Damage{Dice: dicey.MustParse("1d4"), Element: ElementBlunt},
 	Range:  2,
 }
 
 var weaponBeatstick = Weapon{
-	Name:   "beatstick",
-	Damage: Damage{Dice: dicey.MustParse("2d10")},
-	Range:  2,
+	Name:     "beatstick",
+	Damage:   Damage{Dice: dicey.MustParse("2d10"), Element: ElementBlunt},
+	Range:    2,
+	Accuracy: -10,
 }
 
 var weaponHealingStaff = Weapon{
@@ -21,8 +21,7 @@ func (mw *AttackWindow) Render(scr [][]Glyph) {
 	loc := mw.Char.Loc()
 	m := mw.World.Map(loc.Map)
 	wep := mw.Weapon
-	attackRange := wep.Range
-	highlightRange(scr, loc, m, mw.Self, attackRange, wep.Targeting, Color256(130))
+	highlightAttackRange(scr, loc, m, mw.Self, wep, Color256(130))
 
 	helpheader := "Attack: "
 	if mw.Weapon.Magic {
@@ -48,22 +47,121 @@ func (mw *AttackWindow) Render(scr [][]Glyph) {
 		highlightRange(scr, Loc{Map: loc.Map, X: cursor.x, Y: cursor.y}, m, true, wep.HitboxSize, TargetingFree, ColorOlive)
 	}
 
-	if target, ok := m.TileAt(cursor.x, cursor.y).Top().(*Mob); ok {
+	mw.renderForecast(scr, cursor)
+
+	if target, ok := m.TileAt(cursor.x, cursor.y).Top().(*Mob); ok && mw.World.Sees(mw.Char.Team(), target) {
 		var dmginfo string
 		if wep.Damage.IsValid() {
 			dmgname := "damage"
 			if wep.Damage.Type == DamageHealing {
 				dmgname = "heal"
 			}
-			dmginfo = fmt.Sprintf(" (%s: %s)", dmgname, wep.Damage.Dice.String())
+			dmginfo = fmt.Sprintf(" (%s: %s", dmgname, wep.Damage.Dice.String())
+			if wep.Rolls() {
+				dmginfo += fmt.Sprintf(", %d%% hit", HitChance(mw.World, mw.Char, loc, target, wep))
+			}
+			dmginfo += ")"
+		} else if wep.Rolls() {
+			dmginfo = fmt.Sprintf(" (%d%% hit)", HitChance(mw.World, mw.Char, loc, target, wep))
 		}
-		status := append(append(GlyphsOf(" └"), target.StatusLine(true)...), GlyphsOf(dmginfo)...)
+		status := append(append(GlyphsOf(" └"), mw.Sesh.statusLine(target, true)...), GlyphsOf(dmginfo)...)
 		copyGlyphs(scr[len(scr)-2], status, true)
 	} else {
 		copyString(scr[len(scr)-2], " └", true)
 	}
 }
 
+// forecastWidth is how wide the forecast panel is.
+const forecastWidth = 24
+
+// renderForecast draws a panel predicting what happens to every unit hit by attacking at cursor.
+func (mw *AttackWindow) renderForecast(scr [][]Glyph, cursor Coords) {
+	forecasts := ForecastAttack(mw.World, mw.Char, mw.Weapon, mw.targetsAt(cursor))
+	if len(forecasts) == 0 {
+		return
+	}
+
+	header := mw.Weapon.Name
+	if mw.Weapon.MPCost > 0 {
+		header += fmt.Sprintf(", %d MP", mw.Weapon.MPCost)
+	}
+	if mw.Weapon.Charge > 0 {
+		header += ", charge"
+	}
+	lines := [][]Glyph{GlyphsOf(header, StyleBold)}
+	for _, fc := range forecasts {
+		name := Concat(mw.Sesh.mobGlyph(fc.Target), " ", fc.Target.NameColored())
+		if aff := fc.Target.Affinities().Of(mw.Weapon.Damage.Element); aff != AffinityNormal && mw.Weapon.Damage.Type != DamageHealing {
+			name = Concat(name, " (", aff.String(), ")")
+		}
+		lines = append(lines, name)
+		if fc.Max != 0 {
+			dmgname := "dmg"
+			if fc.Heals() {
+				dmgname = "heal"
+			}
+			line := Concat(" ", dmgname, " ", ColorDamage(fc.Min), "-", ColorDamage(fc.Avg), "-", ColorDamage(fc.Max))
+			if mw.Weapon.Rolls() {
+				line = Concat(line, fmt.Sprintf(" %d%%", fc.Hit))
+			}
+			lines = append(lines, line)
+			line = Concat(" HP ", fc.Target.HPText(), " → ", fc.HP)
+			if fc.Kill > 0 {
+				line = Concat(line, GlyphsOf(fmt.Sprintf(" kill %d%%", fc.Kill), StyleFG(ColorBrightRed)))
+			}
+			lines = append(lines, line)
+		} else if mw.Weapon.Rolls() {
+			lines = append(lines, GlyphsOf(fmt.Sprintf(" %d%% hit", fc.Hit)))
+		}
+		if fc.Cover > 0 {
+			lines = append(lines, GlyphsOf(fmt.Sprintf(" cover -%d%%", fc.Cover), StyleFG(Color256(250))))
+		}
+		for _, buff := range fc.Buffs {
+			lines = append(lines, GlyphsOf(" +"+buff))
+		}
+	}
+
+	// keep out of the way of the cursor
+	x := len(scr[0]) - forecastWidth
+	if cursor.x >= x-1 {
+		x = 0
+	}
+	drawPanel(scr, lines, x, forecastWidth, Color256(235))
+}
+
+// targetsAt returns the units that attacking the given tile would hit, as far as we can see.
+func (mw *AttackWindow) targetsAt(at Coords) []*Mob {
+	var seen []*Mob
+	for _, target := range mw.allTargetsAt(at) {
+		if mw.World.Sees(mw.Char.Team(), target) {
+			seen = append(seen, target)
+		}
+	}
+	return seen
+}
+
+func (mw *AttackWindow) allTargetsAt(at Coords) []*Mob {
+	loc := mw.Char.Loc()
+	m := mw.World.Map(loc.Map)
+	wep := mw.Weapon
+	targetLoc := Loc{Map: m.Name, X: at.x, Y: at.y}
+	if wep.Magic {
+		if !withinRange(loc, m, true, wep.Range, wep.Targeting, at.x, at.y) {
+			return nil
+		}
+		targets, _ := findTargets(targetLoc, m, true, wep.HitboxSize, wep.Hitbox)
+		return targets
+	}
+	if wep.Targeting == TargetingCross && loc.X != at.x && loc.Y != at.y {
+		return nil
+	}
+	target, blocked, path := m.Raycast(loc, targetLoc, false)
+	if target == nil || blocked || !target.Attackable() || len(path) > wep.RangeFrom(m, loc, targetLoc) {
+		return nil
+	}
+	return []*Mob{target}
+}
+
 func (mw *AttackWindow) Input(input string) bool {
 	if mw.Readonly {
 		return false
@@ -113,60 +211,18 @@ func (mw *AttackWindow) Click(click Coords) bool {
 	}
 	// fmt.Println("attack click", x, y)
 	loc := mw.Char.Loc()
-	m := mw.World.Map(loc.Map)
-	wep := mw.Weapon
-	targetLoc := Loc{Map: m.Name, X: click.x, Y: click.y}
-	canAttack := wep.Magic
-	var targets []*Mob
-	var projpath []Loc
-	var hitlocs []Loc
-	if wep.Magic {
-		if !withinRange(loc, m, true, wep.Range, wep.Targeting, click.x, click.y) {
-			mw.Sesh.Bell()
-			return true
-		}
-		t, hit := findTargets(targetLoc, m, true, wep.HitboxSize, wep.Hitbox)
-		if len(t) == 0 {
-			mw.Sesh.Bell()
-			return true
-		}
-		targets = t
-		hitlocs = hit
-		_, _, projpath = m.Raycast(loc, targetLoc, true)
-	} else {
-		target, blocked, path := m.Raycast(loc, targetLoc, false)
-		if (target == nil && !blocked) || (target != nil && !target.Attackable()) {
-			mw.Sesh.Bell()
-			return true
-		}
-		if len(path) > wep.Range ||
-			(wep.Targeting == TargetingCross && ((loc.X != click.x) && (loc.Y != click.y))) {
-			// out of range
-			mw.Sesh.Bell()
-			return true
-		}
-		if blocked {
-			mw.Sesh.Send(Concat(
-				mw.Char.NameColored(),
-				"'s attack is obstructed.",
-			))
-		} else {
-			canAttack = true
-			targets = []*Mob{target}
-			projpath = path
-		}
-	}
-
-	if !canAttack {
+	target := Loc{Map: loc.Map, X: click.x, Y: click.y}
+	switch err := mw.World.StartAttack(mw.Char, mw.Weapon, target); err {
+	case nil:
+	case errObstructed:
+		mw.Sesh.Send(Concat(
+			mw.Char.NameColored(),
+			"'s attack is obstructed.",
+		))
+		return true
+	default:
+		mw.Sesh.Bell()
 		return true
-	}
-
-	mw.World.push <- &AttackState{
-		Char:     mw.Char,
-		Targets:  targets,
-		Weapon:   wep,
-		ProjPath: projpath,
-		HitLocs:  hitlocs,
 	}
 
 	mw.done = true
@@ -218,6 +274,40 @@ func highlightRange(scr [][]Glyph, loc Loc, m *Map, selfOK bool, size int, targe
 	}
 }
 
+// highlightAttackRange is like highlightRange, but takes height into account.
+func highlightAttackRange(scr [][]Glyph, loc Loc, m *Map, selfOK bool, wep Weapon, bgColor Color) {
+	// the most range we could possibly get from height, see Weapon.RangeFrom
+	size := wep.Range + max(0, m.TileAtLoc(loc).Height/2)
+	for y := loc.Y - size; y <= loc.Y+size; y++ {
+		if y < 0 {
+			continue
+		}
+		if y >= m.Height() {
+			break
+		}
+		for x := loc.X - size; x <= loc.X+size; x++ {
+			if x < 0 {
+				continue
+			}
+			if x >= m.Width() {
+				break
+			}
+			if !selfOK && loc.X == x && loc.Y == y {
+				continue
+			}
+			if abs(loc.X-x)+abs(loc.Y-y) > wep.RangeFrom(m, loc, Loc{Map: loc.Map, X: x, Y: y}) {
+				continue
+			}
+			if wep.Targeting == TargetingCross {
+				if (loc.X != x) && (loc.Y != y) {
+					continue
+				}
+			}
+			scr[y][x].BG = bgColor
+		}
+	}
+}
+
 func withinRange(loc Loc, m *Map, selfOK bool, size int, targeting TargetingType, targetX, targetY int) bool {
 	for y := loc.Y - size; y <= loc.Y+size; y++ {
 		if y < 0 {
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CommandWindow is a command line for typing orders instead of using the cursor,
+// opened with : in GameWindow. See commandHelp for the commands.
+type CommandWindow struct {
+	World *World
+	Sesh  *Sesh
+	Game  *GameWindow
+
+	line    string
+	history int // how far back in Game.history we are, for the up and down keys
+
+	done bool
+}
+
+var commandHelp = []string{
+	"Commands: move X Y, attack UNIT or X Y, cast SPELL UNIT or X Y (heals and buffs default to yourself), use, wait [north/east/south/west], look UNIT or X Y, units, help",
+	"TAB completes unit and spell names, ESC cancels.",
+}
+
+var commandNames = []string{"attack", "cast", "help", "look", "move", "units", "use", "wait"}
+
+func (cw *CommandWindow) Render(scr [][]Glyph) {
+	copyString(scr[len(scr)-1], ":"+cw.line, true)
+}
+
+func (cw *CommandWindow) Cursor() Coords {
+	return Coords{x: min(len([]rune(cw.line))+1, cw.Sesh.disp.w-1), y: cw.Sesh.disp.h - 1}
+}
+
+func (cw *CommandWindow) Input(input string) bool {
+	switch input {
+	case ArrowKeyUp:
+		cw.recall(1)
+		return true
+	case ArrowKeyDown:
+		cw.recall(-1)
+		return true
+	}
+	if strings.HasPrefix(input, "\033") && len(input) > 1 {
+		// some other escape sequence
+		return true
+	}
+	for _, r := range input {
+		switch r {
+		case EscKey:
+			cw.done = true
+			return true
+		case EnterKey, '\n':
+			cw.done = true
+			cw.run(cw.line)
+			return true
+		case TabKey:
+			cw.complete()
+		case BackspaceKey, '\b':
+			if runes := []rune(cw.line); len(runes) > 0 {
+				cw.line = string(runes[:len(runes)-1])
+			}
+		default:
+			if r >= ' ' {
+				cw.line += string(r)
+			}
+		}
+	}
+	return true
+}
+
+// recall replaces the line with one from the command history.
+func (cw *CommandWindow) recall(delta int) {
+	hist := cw.Game.history
+	cw.history = clamp(cw.history+delta, 0, len(hist))
+	if cw.history == 0 {
+		cw.line = ""
+		return
+	}
+	cw.line = hist[len(hist)-cw.history]
+}
+
+func (cw *CommandWindow) Click(_ Coords) bool {
+	return true
+}
+
+func (cw *CommandWindow) Mouseover(_ Coords) bool {
+	return false
+}
+
+func (cw *CommandWindow) ShouldRemove() bool {
+	return cw.done
+}
+
+func (cw *CommandWindow) close() {
+	cw.done = true
+}
+
+func (cw *CommandWindow) fail(format string, args ...interface{}) {
+	cw.Sesh.Bell()
+	cw.Sesh.Send(GlyphsOf(fmt.Sprintf(format, args...)))
+}
+
+// run carries out a command, telling the player what went wrong if it can't.
+func (cw *CommandWindow) run(line string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	gw := cw.Game
+	gw.history = append(gw.history, line)
+	cmd, args := strings.ToLower(fields[0]), fields[1:]
+
+	switch cmd {
+	case "help", "?":
+		for _, help := range commandHelp {
+			cw.Sesh.Send(GlyphsOf(help))
+		}
+		return
+	case "look", "l":
+		cw.look(args)
+		return
+	case "units":
+		gw.showUnits()
+		return
+	case "move", "m", "attack", "a", "cast", "c", "use", "u", "wait", "w", "n":
+	default:
+		cw.fail("Unknown command: %s. Type help for a list.", fields[0])
+		return
+	}
+
+	if !gw.myTurn() {
+		cw.fail("It's not your turn.")
+		return
+	}
+	m, ok := gw.World.Up().(*Mob)
+	if !ok {
+		return
+	}
+
+	switch cmd {
+	case "move", "m":
+		cw.move(m, args)
+	case "attack", "a":
+		cw.attack(m, m.Weapon(), args)
+	case "cast", "c":
+		spell, rest, ok := cw.findSpell(m, args)
+		if !ok {
+			cw.fail("%s doesn't know that spell. Spells: %s", m.Name(), strings.Join(spellNames(m), ", "))
+			return
+		}
+		if !gw.canAct() || !canCast(cw.Sesh, m, spell) {
+			return
+		}
+		cw.attack(m, spell, rest)
+	case "use", "u":
+		gw.showUse()
+	case "wait", "w", "n":
+		if len(args) > 0 {
+			dir, ok := parseDirection(args[0])
+			if !ok {
+				cw.fail("Which way? north, east, south or west.")
+				return
+			}
+			m.Face(dir)
+		}
+		gw.endTurn()
+	}
+}
+
+func (cw *CommandWindow) move(m *Mob, args []string) {
+	gw := cw.Game
+	if !gw.canMove() {
+		cw.fail("%s can't move right now.", m.Name())
+		return
+	}
+	at, ok := cw.parseLoc(m, args)
+	if !ok {
+		return
+	}
+	loc := m.Loc()
+	path := gw.World.Map(loc.Map).Flood(loc, m.MoveRange(), m).PathTo(at.X, at.Y)
+	if len(path) == 0 {
+		cw.fail("%s can't get to %d, %d.", m.Name(), at.X, at.Y)
+		return
+	}
+	gw.startLoc = loc
+	gw.World.push <- &MoveState{Obj: m, Path: path}
+	gw.moved = true
+	if !gw.canDoSomething() {
+		gw.nextTurn()
+	}
+}
+
+func (cw *CommandWindow) attack(m *Mob, wep Weapon, args []string) {
+	gw := cw.Game
+	if !gw.canAct() {
+		cw.fail("%s can't act right now.", m.Name())
+		return
+	}
+	at := m.Loc()
+	if len(args) > 0 || !wep.Magic || !helpfulSpell(wep) {
+		var ok bool
+		at, ok = cw.parseTarget(m, wep, args)
+		if !ok {
+			return
+		}
+	}
+	switch err := gw.World.StartAttack(m, wep, at); err {
+	case nil:
+	case errObstructed:
+		cw.fail("%s's attack is obstructed.", m.Name())
+		return
+	default:
+		cw.fail("Can't use %s on %d, %d: %v.", wep.Name, at.X, at.Y, err)
+		return
+	}
+	gw.acted = true
+	if !gw.canDoSomething() {
+		gw.nextTurn()
+	}
+}
+
+// helpfulSpell returns true if spell heals or only buffs, so casting it without a target can default to yourself.
+func helpfulSpell(spell Weapon) bool {
+	if spell.Damage.Type == DamageHealing {
+		return true
+	}
+	for _, buff := range spell.Buffs {
+		if !helpfulBuffs[buff] {
+			return false
+		}
+	}
+	return len(spell.Buffs) > 0
+}
+
+func (cw *CommandWindow) look(args []string) {
+	gw := cw.Game
+	var from *Mob
+	if m, ok := gw.World.Up().(*Mob); ok {
+		from = m
+	}
+	at, ok := cw.parseTarget(from, Weapon{}, args)
+	if !ok {
+		return
+	}
+	status, info := lookAt(gw.World, gw.Team, at, cw.Sesh.accessible)
+	cw.Sesh.Send(Concat(fmt.Sprintf("%d, %d: ", at.X, at.Y), status))
+	if len(info) > 0 {
+		cw.Sesh.Send(info)
+	}
+}
+
+// parseLoc reads X Y coordinates from args.
+func (cw *CommandWindow) parseLoc(m *Mob, args []string) (Loc, bool) {
+	if len(args) != 2 {
+		cw.fail("Where to? Give X and Y coordinates, like: 12 7")
+		return Loc{}, false
+	}
+	x, errX := strconv.Atoi(args[0])
+	y, errY := strconv.Atoi(args[1])
+	mapName := cw.World.current.Name
+	if m != nil {
+		mapName = m.Loc().Map
+	}
+	if errX != nil || errY != nil {
+		cw.fail("Invalid coordinates: %s %s", args[0], args[1])
+		return Loc{}, false
+	}
+	at := Loc{Map: mapName, X: x, Y: y}
+	if mp := cw.World.Map(mapName); x < 0 || y < 0 || x >= mp.Width() || y >= mp.Height() {
+		cw.fail("%d, %d is off the map.", x, y)
+		return Loc{}, false
+	}
+	return at, true
+}
+
+// parseTarget reads either coordinates or a unit's name from args.
+// If more than one unit goes by that name, it picks the closest one m can use wep on, or just the closest.
+func (cw *CommandWindow) parseTarget(m *Mob, wep Weapon, args []string) (Loc, bool) {
+	if len(args) == 2 {
+		if _, err := strconv.Atoi(args[0]); err == nil {
+			return cw.parseLoc(m, args)
+		}
+	}
+	if len(args) == 0 {
+		cw.fail("At what? Give a unit's name or X and Y coordinates.")
+		return Loc{}, false
+	}
+	name := strings.Join(args, " ")
+	targets := cw.unitsNamed(name)
+	if len(targets) == 0 {
+		cw.fail("Can't see anyone called %s.", name)
+		return Loc{}, false
+	}
+	if m == nil {
+		return targets[0].Loc(), true
+	}
+	from := m.Loc()
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := targets[i].Loc(), targets[j].Loc()
+		return abs(a.X-from.X)+abs(a.Y-from.Y) < abs(b.X-from.X)+abs(b.Y-from.Y)
+	})
+	if wep.Name != "" {
+		for _, target := range targets {
+			if _, err := cw.World.aimAttack(m, wep, target.Loc()); err == nil {
+				return target.Loc(), true
+			}
+		}
+	}
+	return targets[0].Loc(), true
+}
+
+// unitsNamed returns the living units we can see called name, or whose names start with it.
+func (cw *CommandWindow) unitsNamed(name string) []*Mob {
+	var exact, prefix []*Mob
+	for _, mob := range cw.visibleUnits() {
+		switch {
+		case strings.EqualFold(mob.Name(), name):
+			exact = append(exact, mob)
+		case hasPrefixFold(mob.Name(), name):
+			prefix = append(prefix, mob)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return prefix
+}
+
+func (cw *CommandWindow) visibleUnits() []*Mob {
+	var units []*Mob
+	for _, obj := range cw.Game.Map.Objects {
+		if mob, ok := obj.(*Mob); ok && !mob.Dead() && cw.World.Sees(cw.Game.Team, mob) {
+			units = append(units, mob)
+		}
+	}
+	return units
+}
+
+// findSpell finds the spell named at the start of args (spell names can have spaces),
+// returning the rest of args.
+func (cw *CommandWindow) findSpell(m *Mob, args []string) (Weapon, []string, bool) {
+	for n := len(args); n > 0; n-- {
+		if spell, ok := m.Spell(strings.Join(args[:n], " ")); ok {
+			return spell, args[n:], true
+		}
+	}
+	return Weapon{}, nil, false
+}
+
+func spellNames(m *Mob) []string {
+	names := make([]string, 0, len(m.Spells()))
+	for _, spell := range m.Spells() {
+		names = append(names, spell.Name)
+	}
+	return names
+}
+
+// complete finishes the word (or name) being typed, or lists the options if there's more than one.
+func (cw *CommandWindow) complete() {
+	fields := strings.Fields(cw.line)
+	trailing := strings.HasSuffix(cw.line, " ")
+	if len(fields) == 0 || (len(fields) == 1 && !trailing) {
+		cw.completeFrom(strings.TrimLeft(cw.line, " "), commandNames)
+		return
+	}
+
+	var options []string
+	cmd := strings.ToLower(fields[0])
+	rest := strings.TrimLeft(cw.line[strings.Index(cw.line, fields[0])+len(fields[0]):], " ")
+	m, _ := cw.World.Up().(*Mob)
+	switch cmd {
+	case "cast", "c":
+		if m == nil {
+			return
+		}
+		for _, name := range spellNames(m) {
+			if hasPrefixFold(rest, name+" ") {
+				// already have the spell, so complete the target
+				rest = strings.TrimLeft(rest[len(name):], " ")
+				options = cw.unitNames()
+				break
+			}
+		}
+		if options != nil {
+			break
+		}
+		options = spellNames(m)
+	case "attack", "a", "look", "l":
+		options = cw.unitNames()
+	case "wait", "w", "n":
+		for dir := North; dir <= West; dir++ {
+			options = append(options, dir.String())
+		}
+	}
+	cw.completeFrom(rest, options)
+}
+
+// completeFrom completes partial, which is at the end of the line, with one of options.
+func (cw *CommandWindow) completeFrom(partial string, options []string) {
+	var matches []string
+	for _, opt := range options {
+		if hasPrefixFold(opt, partial) {
+			matches = append(matches, opt)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		cw.Sesh.Bell()
+		return
+	case 1:
+		cw.line = cw.line[:len(cw.line)-len(partial)] + matches[0] + " "
+		return
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !hasPrefixFold(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(partial) {
+		cw.line = cw.line[:len(cw.line)-len(partial)] + common
+		return
+	}
+	cw.Sesh.Send(GlyphsOf(strings.Join(matches, ", ")))
+}
+
+// unitNames returns the names of the units we can see, without duplicates.
+func (cw *CommandWindow) unitNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, mob := range cw.visibleUnits() {
+		if !seen[mob.Name()] {
+			seen[mob.Name()] = true
+			names = append(names, mob.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+var (
+	_ Window = (*CommandWindow)(nil)
+)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandWindow(t *testing.T) {
+	r := require.New(t)
+
+	w, m := newTestWorld(t, "oneroom")
+	mage := spawn(w, 38, 13, &Mob{name: "Mage", maxMP: 20, team: PlayerTeam, base: Stats{Move: 4}, weapon: weaponSword, spells: []Weapon{spellFireball, spellHeal}})
+	kobold := spawn(w, 39, 13, &Mob{name: "Kobold", maxMP: 20, team: 1, base: Stats{Move: 4}, weapon: weaponSword})
+	spawn(w, 42, 13, &Mob{name: "Kobold shaman", maxMP: 20, team: 1, base: Stats{Move: 4}, weapon: weaponSword})
+	w.up = mage
+
+	sesh := NewSesh(&testConn{}, w)
+	gw := &GameWindow{World: w, Sesh: sesh, Team: PlayerTeam, Map: m}
+	sesh.win = gw
+	sesh.PushWindow(gw)
+	typed := func(keys ...string) *CommandWindow {
+		gw.Input(":")
+		cw := sesh.TopWindow().(*CommandWindow)
+		for _, key := range keys {
+			cw.Input(key)
+		}
+		return cw
+	}
+
+	// tab completion
+	cw := typed("at", "\t", "ko", "\t")
+	r.Equal("attack Kobold", cw.line)
+	cw.Input(" s\t")
+	r.Equal("attack Kobold shaman ", cw.line)
+	cw.Input("\033")
+	r.True(cw.done)
+	cw = typed("cast f\t")
+	r.Equal("cast fireball ", cw.line)
+	cw.Input("kobold s\t")
+	r.Equal("cast fireball Kobold shaman ", cw.line)
+	cw.Input("\033")
+
+	// bad orders explain themselves
+	typed("move 100 2\r")
+	r.Contains(PlainText(gw.Msgs[len(gw.Msgs)-1]), "off the map")
+	r.False(gw.moved)
+	typed("cast blizzard 1 1\r")
+	r.Contains(PlainText(gw.Msgs[len(gw.Msgs)-1]), "fireball, heal")
+	typed("cast fireball\r")
+	r.Contains(PlainText(gw.Msgs[len(gw.Msgs)-1]), "At what?")
+	r.False(gw.acted)
+
+	// attacking by name picks the kobold we can reach
+	typed("attack kobold\r")
+	r.True(gw.acted)
+	as := (<-w.push).(*AttackState)
+	r.Equal([]*Mob{kobold}, as.Targets)
+
+	typed("look 39 13\r")
+	r.Contains(PlainText(gw.Msgs[len(gw.Msgs)-1]), "Kobold")
+
+	typed("move 38 11\r")
+	r.True(gw.moved)
+	r.IsType(&MoveState{}, <-w.push)
+
+	typed("wait west\r")
+	r.Equal(West, mage.Facing())
+	r.IsType(NextTurnState{}, <-w.pushBottom)
+
+	// up recalls earlier commands
+	cw = typed(ArrowKeyUp, ArrowKeyUp)
+	r.Equal("move 38 11", cw.line)
+	r.Equal([]string{"move 100 2", "cast blizzard 1 1", "cast fireball", "attack kobold", "look 39 13", "move 38 11", "wait west"}, gw.history)
+}
@@ -75,8 +75,11 @@ func (cm *ContextMenu) Render(scr [][]Glyph) {
 		if !ok {
 			return
 		}
-		status := append(GlyphsOf(" └"), target.StatusLine(true)...)
-		copyGlyphs(scr[len(scr)-2], status, true)
+		// don't give away units hidden by fog of war
+		if cm.sesh.win == nil || cm.world.Sees(cm.sesh.win.Team, target) {
+			status := append(GlyphsOf(" └"), cm.sesh.statusLine(target, true)...)
+			copyGlyphs(scr[len(scr)-2], status, true)
+		}
 	}
 
 	// menu
@@ -101,9 +104,9 @@ func (cm *ContextMenu) Cursor() Coords {
 
 func (cm *ContextMenu) Input(in string) bool {
 	switch in {
-	case string(EscKey):
+	case string(rune(EscKey)):
 		cm.done = true
-	case string(EnterKey):
+	case string(rune(EnterKey)):
 		cm.activate()
 	case ArrowKeyUp:
 		cm.selected--
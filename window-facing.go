@@ -0,0 +1,95 @@
+package main
+
+// FacingWindow lets the player pick which way a unit faces before ending its turn.
+type FacingWindow struct {
+	World    *World
+	Sesh     *Sesh
+	Char     *Mob
+	done     bool
+	callback func() bool
+}
+
+func (fw *FacingWindow) Render(scr [][]Glyph) {
+	loc := fw.Char.Loc()
+	m := fw.World.Map(loc.Map)
+	for dir := North; dir <= West; dir++ {
+		dx, dy := dir.Delta()
+		x, y := loc.X+dx, loc.Y+dy
+		if x < 0 || y < 0 || x >= m.Width() || y >= m.Height() || y >= len(scr) {
+			continue
+		}
+		scr[y][x].Rune = dir.Arrow()
+		scr[y][x].FG = fw.Char.Glyph().FG
+		if dir == fw.Char.Facing() {
+			scr[y][x].BG = ColorOlive
+		} else {
+			scr[y][x].BG = ColorBlack
+		}
+	}
+	copyString(scr[len(scr)-1], "Facing: arrow keys or click to turn, . or ENTER to end turn; ESC to cancel", true)
+}
+
+func (fw *FacingWindow) Cursor() Coords {
+	return fw.Char.Loc().AsCoords()
+}
+
+func (fw *FacingWindow) Input(input string) bool {
+	if fw.World.Busy() {
+		// still walking, let the move finish first
+		return true
+	}
+	if len(input) == 1 {
+		switch input[0] {
+		case EscKey:
+			fw.done = true
+			return true
+		case '.', EnterKey:
+			fw.confirm()
+			return true
+		}
+	}
+	switch input {
+	case ArrowKeyUp, "8":
+		fw.Char.Face(North)
+	case ArrowKeyRight, "6":
+		fw.Char.Face(East)
+	case ArrowKeyDown, "2":
+		fw.Char.Face(South)
+	case ArrowKeyLeft, "4":
+		fw.Char.Face(West)
+	}
+	return true
+}
+
+func (fw *FacingWindow) Click(coords Coords) bool {
+	if fw.World.Busy() {
+		return true
+	}
+	loc := fw.Char.Loc()
+	if coords.x == loc.X && coords.y == loc.Y {
+		fw.confirm()
+		return true
+	}
+	fw.Char.FaceToward(Loc{Map: loc.Map, X: coords.x, Y: coords.y})
+	if abs(coords.x-loc.X)+abs(coords.y-loc.Y) == 1 {
+		fw.confirm()
+	}
+	return true
+}
+
+func (fw *FacingWindow) confirm() {
+	fw.done = true
+	fw.callback()
+}
+
+func (fw *FacingWindow) Mouseover(_ Coords) bool {
+	return true
+}
+
+func (fw *FacingWindow) ShouldRemove() bool {
+	return fw.done
+}
+
+var (
+	_ Window = (*FacingWindow)(nil)
+)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 )
 
 type GameWindow struct {
@@ -11,10 +12,14 @@ type GameWindow struct {
 	Team  int
 	Map   *Map
 
-	turnID   int
-	moved    bool
-	acted    bool
-	startLoc Loc
+	turnID    int
+	moved     bool
+	acted     bool
+	startLoc  Loc
+	heights   bool // show height overlay
+	showOrder bool // show turn order panel, off by default since it covers part of the map
+	hover     Coords
+	history   []string // typed commands, see CommandWindow
 
 	done bool
 }
@@ -36,6 +41,17 @@ func (gw *GameWindow) Input(in string) bool {
 	case "R":
 		gw.Sesh.redraw()
 		return true
+	case "h":
+		gw.heights = !gw.heights
+		return true
+	case "o":
+		gw.showOrder = !gw.showOrder
+		return true
+	case ":":
+		gw.Sesh.PushWindow(&CommandWindow{World: gw.World, Sesh: gw.Sesh, Game: gw})
+		return true
+	case "l":
+		return gw.showUnits()
 	}
 
 	if !gw.myTurn() {
@@ -51,21 +67,20 @@ func (gw *GameWindow) Input(in string) bool {
 		return gw.nextTurn()
 	case "c":
 		return gw.showCast()
+	case "u":
+		return gw.showUse()
 	case "q", ";":
 		gw.Sesh.PushWindow(&FarlookWindow{
 			World:         gw.World,
 			Sesh:          gw.Sesh,
 			Char:          gw.World.Up(),
+			Team:          gw.Team,
 			cursorHandler: newCursorHandlerOn(gw.World, gw.World.Up()),
 		})
 	case "r":
 		return gw.resetMove()
 	case "t", "i", "\t":
-		gw.Sesh.PushWindow(&TeamWindow{
-			World: gw.World,
-			Sesh:  gw.Sesh,
-			Team:  gw.World.battle.Teams[PlayerTeam],
-		})
+		return gw.showTeam()
 	case "W":
 		// gw.World.winBattle()
 	}
@@ -78,17 +93,20 @@ func (gw *GameWindow) myTurn() bool {
 		return false
 	}
 
-	if m, ok := gw.World.Up().(*Mob); ok {
-		if m.Team() != gw.Team {
+	switch up := gw.World.Up().(type) {
+	case *Mob:
+		if up.Team() != gw.Team || up.AIControlled(gw.World) {
 			return false
 		}
+	case *Charge:
+		return false
 	}
 
 	return true
 }
 
 func (gw *GameWindow) showMove() bool {
-	if gw.moved {
+	if !gw.canMove() {
 		return true
 	}
 	up := gw.World.Up()
@@ -113,7 +131,7 @@ func (gw *GameWindow) showMove() bool {
 }
 
 func (gw *GameWindow) showAttack() bool {
-	if gw.acted {
+	if !gw.canAct() {
 		return true
 	}
 	up := gw.World.Up()
@@ -137,7 +155,7 @@ func (gw *GameWindow) showAttack() bool {
 }
 
 func (gw *GameWindow) showCast() bool {
-	if gw.acted {
+	if !gw.canAct() {
 		return true
 	}
 	up := gw.World.Up()
@@ -176,7 +194,7 @@ func (gw *GameWindow) showCast() bool {
 }
 
 func (gw *GameWindow) showCastContext(target *Mob) bool {
-	if gw.acted {
+	if !gw.canAct() {
 		return true
 	}
 	up := gw.World.Up()
@@ -188,6 +206,9 @@ func (gw *GameWindow) showCastContext(target *Mob) bool {
 
 		cbfunc := func(spell Weapon) func() {
 			return func() {
+				if !canCast(gw.Sesh, m, spell) {
+					return
+				}
 				loc := m.Loc()
 
 				gw.Sesh.PushWindow(&AttackWindow{
@@ -211,7 +232,7 @@ func (gw *GameWindow) showCastContext(target *Mob) bool {
 		var actions = make([]MenuItem, 0, len(spells))
 		for _, spell := range spells {
 			actions = append(actions, MenuItem{
-				text:   fmt.Sprintf("%s (%dMP)", spell.Name, spell.MPCost),
+				text:   spellLabel(m, spell),
 				action: cbfunc(spell),
 			})
 		}
@@ -222,7 +243,110 @@ func (gw *GameWindow) showCastContext(target *Mob) bool {
 	return true
 }
 
+// showUse lists the doors, levers, etc. next to the unit that's up.
+func (gw *GameWindow) showUse() bool {
+	if !gw.canAct() {
+		return true
+	}
+	m, ok := gw.World.Up().(*Mob)
+	if !ok {
+		return true
+	}
+	tiles := gw.World.UsableNear(m)
+	if len(tiles) == 0 {
+		gw.Sesh.Bell()
+		return true
+	}
+	items := make([]MenuItem, 0, len(tiles))
+	for _, tile := range tiles {
+		tile := tile
+		items = append(items, MenuItem{
+			text: strings.Title(tile.Use) + " " + tile.TerrainName(),
+			action: func() {
+				gw.World.push <- &UseState{Char: m, Tile: tile}
+				gw.acted = true
+				if !gw.canDoSomething() {
+					gw.nextTurn()
+				}
+			},
+		})
+	}
+	gw.Sesh.PushWindow(newContextMenu(gw.World, gw.Sesh, m.Loc().AsCoords(), items))
+	return true
+}
+
+// showTeam opens the team window, where units can also be handed over to the AI.
+func (gw *GameWindow) showTeam() bool {
+	gw.Sesh.PushWindow(&TeamWindow{
+		World:    gw.World,
+		Sesh:     gw.Sesh,
+		Team:     gw.World.battle.Teams[PlayerTeam],
+		callback: gw.handOff,
+	})
+	return true
+}
+
+// showUnits lists the units we can see, with where they are from the unit that's up.
+func (gw *GameWindow) showUnits() bool {
+	gw.Sesh.PushWindow(&UnitListWindow{
+		World: gw.World,
+		Sesh:  gw.Sesh,
+		Lines: nearbyUnits(gw.World, gw.Team, gw.listFrom()),
+	})
+	return true
+}
+
+// listFrom is where to measure distances from for unit lists: the unit that's up if we can see it,
+// otherwise our first unit still standing.
+func (gw *GameWindow) listFrom() Loc {
+	if up := gw.World.Up(); up != nil && gw.World.Sees(gw.Team, up) {
+		return up.Loc()
+	}
+	for _, unit := range gw.World.player.Units {
+		if !unit.Dead() {
+			return unit.Loc()
+		}
+	}
+	return Loc{Map: gw.Map.Name}
+}
+
+// handOff lets the AI finish the current unit's turn if it was switched to AI control partway through.
+// It goes on the bottom of the state stack, so a move or attack that's still playing out finishes first.
+func (gw *GameWindow) handOff() {
+	m, ok := gw.World.Up().(*Mob)
+	if !ok || m.Team() != gw.Team || m.Dead() || !m.AIControlled(gw.World) {
+		return
+	}
+	gw.World.pushBottom <- &HandOffState{
+		self:  m,
+		moved: gw.moved,
+		acted: gw.acted,
+	}
+	gw.moved = false
+	gw.acted = false
+}
+
+// nextTurn asks which way to face, then ends the turn.
 func (gw *GameWindow) nextTurn() bool {
+	up := gw.World.Up()
+	if m, ok := up.(*Mob); ok {
+		if m.Team() != gw.Team {
+			return true
+		}
+		if !m.Dead() {
+			gw.Sesh.PushWindow(&FacingWindow{
+				World:    gw.World,
+				Sesh:     gw.Sesh,
+				Char:     m,
+				callback: gw.endTurn,
+			})
+			return true
+		}
+	}
+	return gw.endTurn()
+}
+
+func (gw *GameWindow) endTurn() bool {
 	up := gw.World.Up()
 	if m, ok := up.(*Mob); ok {
 		if m.Team() != gw.Team {
@@ -237,7 +361,19 @@ func (gw *GameWindow) nextTurn() bool {
 }
 
 func (gw *GameWindow) canDoSomething() bool {
-	return !gw.moved || !gw.acted
+	return gw.canMove() || gw.canAct()
+}
+
+// canMove returns true if the unit that's up hasn't moved yet and isn't held in place.
+func (gw *GameWindow) canMove() bool {
+	m, ok := gw.World.Up().(*Mob)
+	return !gw.moved && (!ok || m.CanMove())
+}
+
+// canAct returns true if the unit that's up hasn't acted yet and isn't stunned or asleep.
+func (gw *GameWindow) canAct() bool {
+	m, ok := gw.World.Up().(*Mob)
+	return !gw.acted && (!ok || m.CanAct())
 }
 
 func (gw *GameWindow) resetMove() bool {
@@ -258,6 +394,7 @@ func (gw *GameWindow) resetMove() bool {
 func (gw *GameWindow) Render(scr [][]Glyph) {
 	// render map
 	m := gw.Map
+	fog := gw.World.Vision(gw.Team)
 nextline:
 	for y := 0; y < len(m.Tiles); y++ {
 		if y >= len(scr) {
@@ -268,7 +405,22 @@ nextline:
 				continue nextline
 			}
 			tile := m.TileAt(x, y)
-			scr[y][x] = tile.Glyph()
+			switch {
+			case fog == nil || fog.Visible(x, y):
+				scr[y][x] = tile.Glyph()
+				if mob, ok := tile.Top().(*Mob); ok && mob.steadyBG() != nil {
+					scr[y][x].BG = gw.Sesh.mobGlyph(mob).BG
+				}
+			case fog.Seen(x, y):
+				scr[y][x] = dimmed(tile.Ground)
+				continue
+			default:
+				scr[y][x] = GlyphOf(' ')
+				continue
+			}
+			if gw.heights {
+				shadeHeight(&scr[y][x], tile)
+			}
 		}
 	}
 
@@ -284,6 +436,10 @@ nextline:
 		copyGlyphs(scr[1+i*4+2], Concat("HP: ", unit.HPText()), false)
 	}
 
+	if gw.showOrder {
+		gw.renderTimeline(scr)
+	}
+
 	// render combat log
 	const chatLines = 4
 	const bottomUILines = 3 // target info, help etc
@@ -299,9 +455,9 @@ nextline:
 
 	// render current unit status
 	up := gw.World.Up()
-	if up != nil {
+	if up != nil && gw.World.Sees(gw.Team, up) {
 		if mob, ok := up.(*Mob); ok {
-			copyGlyphs(scr[len(scr)-3], mob.StatusLine(false), true)
+			copyGlyphs(scr[len(scr)-3], gw.Sesh.statusLine(mob, false), true)
 		}
 	}
 
@@ -324,26 +480,110 @@ nextline:
 		}
 		helpBar += str
 	}
-	if !gw.moved {
+	if gw.canMove() {
 		pushHelp("m) Move")
-	} else if !gw.acted {
+	} else if gw.moved && !gw.acted {
 		pushHelp("r) Reset move")
 	}
-	if !gw.acted {
+	if gw.canAct() {
 		pushHelp("a) Attack")
 		if mob, ok := up.(*Mob); ok && len(mob.Spells()) > 0 {
 			pushHelp("c) Cast spell")
 		}
+		if mob, ok := up.(*Mob); ok && len(gw.World.UsableNear(mob)) > 0 {
+			pushHelp("u) Use")
+		}
+	}
+	if gw.Sesh.accessible {
+		pushHelp("l) List units")
 	}
-	pushHelp("q) Query t) Team info")
+	pushHelp("q) Query t) Team h) Heights o) Order :) Command")
 	pushHelp("n) Next turn")
 	copyString(scr[len(scr)-1], helpBar, true)
 }
 
+const (
+	timelineLength = 8 // how many upcoming turns the turn order panel shows
+	timelineWidth  = 22
+)
+
+// renderTimeline draws the turn order panel: who goes next, which spells go off, and which buffs run out.
+func (gw *GameWindow) renderTimeline(scr [][]Glyph) {
+	// highlight whatever's being pointed at
+	cursor := gw.hover
+	if top := gw.Sesh.TopWindow(); top != nil && top != Window(gw) {
+		cursor = top.Cursor()
+	}
+	var pointed Object
+	if cursor.IsValid() && gw.World.CanSee(gw.Team, Loc{Map: gw.Map.Name, X: cursor.x, Y: cursor.y}) {
+		pointed = gw.Map.TileAt(cursor.x, cursor.y).Top()
+	}
+
+	lines := [][]Glyph{GlyphsOf("Turn order", StyleBold)}
+	for _, entry := range gw.World.Timeline(timelineLength, true, true) {
+		var line []Glyph
+		switch t := entry.Turner.(type) {
+		case *Mob:
+			if !gw.World.Sees(gw.Team, t) {
+				line = GlyphsOf("? unknown", StyleFG(Color256(245)))
+				break
+			}
+			line = Concat(gw.Sesh.mobGlyph(t), " ", t.NameColored())
+		case *Charge:
+			if !gw.World.Sees(gw.Team, t) {
+				line = GlyphsOf("? unknown spell", StyleFG(Color256(245)))
+				break
+			}
+			line = Concat(t.Glyph(), " ", GlyphsOf(t.Spell().Name, StyleBold))
+		}
+		for _, buff := range entry.Expiring {
+			line = Concat(line, GlyphsOf(" -"+buff, StyleFG(Color256(245))))
+		}
+		if entry.Turner == pointed {
+			ApplyStyle(line, StyleReverse)
+		}
+		lines = append(lines, line)
+	}
+
+	if mob, ok := gw.World.Up().(*Mob); ok && gw.myTurn() && !mob.Dead() {
+		lines = append(lines, nil)
+		if !gw.moved && !gw.acted {
+			lines = append(lines, GlyphsOf(turnsText("wait", gw.World.NextTurnIn(false, false))))
+		} else {
+			lines = append(lines, GlyphsOf(turnsText("end now", gw.World.NextTurnIn(gw.moved, gw.acted))))
+		}
+		if !gw.moved || !gw.acted {
+			lines = append(lines, GlyphsOf(turnsText("move+act", gw.World.NextTurnIn(true, true))))
+		}
+	}
+
+	drawPanel(scr, lines, len(scr[0])-timelineWidth, timelineWidth, Color256(234))
+}
+
+func turnsText(choice string, n int) string {
+	if n < 0 {
+		return choice + ": next turn ?"
+	}
+	return fmt.Sprintf("%s: next in %d", choice, n)
+}
+
+// shadeHeight draws a tile for the height overlay:
+// empty ground shows its height as a digit, and higher tiles get lighter backgrounds.
+func shadeHeight(g *Glyph, tile *Tile) {
+	if tile.Collides {
+		return
+	}
+	if tile.Top() == nil {
+		g.Rune = rune('0' + min(tile.Height, 9))
+		g.FG = ColorWhite
+	}
+	g.BG = Color256(233 + min(tile.Height, 9)*2)
+}
+
 func (gw *GameWindow) Cursor() Coords {
 	up := gw.World.Up()
 	m, ok := up.(*Mob)
-	if !ok {
+	if !ok || !gw.World.Sees(gw.Team, m) {
 		return OriginCoords
 	}
 	loc := m.Loc()
@@ -363,7 +603,7 @@ func (gw *GameWindow) Click(click Coords) bool {
 	uploc := up.Loc()
 	if uploc.X == click.x && uploc.Y == click.y {
 		var items []MenuItem
-		if !gw.moved {
+		if gw.canMove() {
 			items = append(items, MenuItem{
 				text: "Move",
 				action: func() {
@@ -380,6 +620,8 @@ func (gw *GameWindow) Click(click Coords) bool {
 					},
 				})
 			}
+		}
+		if gw.canAct() {
 			items = append(items, MenuItem{
 				text: "Attack",
 				action: func() {
@@ -394,15 +636,19 @@ func (gw *GameWindow) Click(click Coords) bool {
 					},
 				})
 			}
+			if mob, ok := up.(*Mob); ok && len(gw.World.UsableNear(mob)) > 0 {
+				items = append(items, MenuItem{
+					text: "Use",
+					action: func() {
+						gw.showUse()
+					},
+				})
+			}
 		}
 		items = append(items, MenuItem{
 			text: "Team status",
 			action: func() {
-				gw.Sesh.PushWindow(&TeamWindow{
-					World: gw.World,
-					Sesh:  gw.Sesh,
-					Team:  gw.World.battle.Teams[PlayerTeam],
-				})
+				gw.showTeam()
 			},
 		})
 		items = append(items, MenuItem{
@@ -417,9 +663,9 @@ func (gw *GameWindow) Click(click Coords) bool {
 	}
 
 	tile := gw.Map.TileAt(click.x, click.y)
-	if target, ok := tile.Top().(*Mob); ok {
+	if target, ok := tile.Top().(*Mob); ok && gw.World.Sees(gw.Team, target) {
 		// return gw.showAttack()
-		if gw.acted {
+		if !gw.canAct() {
 			return true
 		}
 		items := []MenuItem{
@@ -443,14 +689,16 @@ func (gw *GameWindow) Click(click Coords) bool {
 		return true
 	}
 
-	if !tile.HasCollider() {
+	// units hidden by fog of war shouldn't give themselves away by blocking the click
+	if !tile.HasCollider() || !gw.World.CanSee(gw.Team, Loc{Map: gw.Map.Name, X: click.x, Y: click.y}) {
 		return gw.showMove()
 	}
 	return true
 }
 
-func (gw *GameWindow) Mouseover(_ Coords) bool {
-	return false
+func (gw *GameWindow) Mouseover(coords Coords) bool {
+	gw.hover = coords
+	return true
 }
 
 type GameOverWindow struct {
@@ -8,6 +8,7 @@ type FarlookWindow struct {
 	World *World
 	Sesh  *Sesh
 	Char  Object
+	Team  int // only shows what this team can see in fog of war mode
 
 	*cursorHandler
 
@@ -22,24 +23,53 @@ func (mw *FarlookWindow) Render(scr [][]Glyph) {
 		copyString(scr[len(scr)-2], arrow, true)
 		return
 	}
-	m := mw.World.Map(mw.defaultLoc().Map)
-	tile := m.TileAt(mw.cursor.x, mw.cursor.y)
-	if target, ok := tile.Top().(*Mob); ok {
-		status := append(GlyphsOf(arrow), target.StatusLine(true)...)
-		copyGlyphs(scr[len(scr)-2], status, true)
-	} else {
-		name := "floor"
-		if tile.Collides {
-			if tile.Ground.Rune == ' ' {
-				name = "empty space"
-			} else {
-				name = "wall"
+	at := Loc{Map: mw.defaultLoc().Map, X: mw.cursor.x, Y: mw.cursor.y}
+	status, info := lookAt(mw.World, mw.Team, at, mw.Sesh.accessible)
+	copyGlyphs(scr[len(scr)-2], Concat(arrow, status), true)
+	if len(info) > 0 {
+		copyGlyphs(scr[len(scr)-3], info, true)
+	}
+}
+
+// lookAt describes what team can see at the given tile: a status line,
+// and for units, a line of extra info (which may be empty). Steady leaves out blinking, for accessible mode.
+func lookAt(w *World, team int, at Loc, steady bool) (status, info []Glyph) {
+	tile := w.Map(at.Map).TileAt(at.X, at.Y)
+	if fog := w.Vision(team); fog != nil && !fog.Visible(at.X, at.Y) {
+		desc := "unexplored"
+		glyph := GlyphOf(' ')
+		if fog.Seen(at.X, at.Y) {
+			desc = tile.Describe() + " (out of sight)"
+			glyph = dimmed(tile.Ground)
+		}
+		status = GlyphsOf("[ ] " + desc)
+		status[1] = glyph
+		return status, nil
+	}
+	if target, ok := tile.Top().(*Mob); ok && w.Sees(team, target) {
+		if tile.HasEffects() {
+			info = GlyphsOf(" [ ] on " + tile.Describe())
+			info[2] = tile.Ground
+		}
+		if aff := target.Affinities().Glyphs(); len(aff) > 0 {
+			if len(info) > 0 {
+				info = Concat(info, ";")
 			}
+			info = Concat(info, " ", aff)
 		}
-		status := GlyphsOf(fmt.Sprintf(arrow+"[ ] %s", name))
-		status[3] = tile.Glyph()
-		copyGlyphs(scr[len(scr)-2], status, true)
+		glyph := target.Glyph()
+		if steady {
+			glyph = target.steadyGlyph()
+		}
+		return target.statusLine(true, glyph), info
+	}
+	desc := tile.Describe()
+	if trap, ok := tile.Top().(*Trap); ok {
+		desc = trap.Name() + " on " + desc
 	}
+	status = GlyphsOf("[ ] " + desc)
+	status[1] = tile.Glyph()
+	return status, nil
 }
 
 func (mw *FarlookWindow) defaultLoc() Loc {
@@ -81,7 +81,7 @@ func (cm *ModalMenu) Render(scr [][]Glyph) {
 	}
 	// ApplyStyle(scr[cm.topLeft.y][cm.topLeft.x:cm.topLeft.x+cm.width+2], StyleBG(bg))
 	for i, opt := range cm.options {
-		text := boxV + string('a'+i) + ") " + opt.text + strings.Repeat(" ", cm.width-len(opt.text)-len("a) ")) + boxV
+		text := boxV + string(rune('a'+i)) + ") " + opt.text + strings.Repeat(" ", cm.width-len(opt.text)-len("a) ")) + boxV
 		copyStringOffset(scr[y], text, cm.topLeft.x)
 		if cm.selected == i+1 {
 			ApplyStyle(scr[y][cm.topLeft.x+1:cm.topLeft.x+cm.width+1], StyleReverse)
@@ -100,9 +100,9 @@ func (cm *ModalMenu) Cursor() Coords {
 
 func (cm *ModalMenu) Input(in string) bool {
 	switch in {
-	case string(EscKey):
+	case string(rune(EscKey)):
 		cm.done = true
-	case string(EnterKey):
+	case string(rune(EnterKey)):
 		cm.activate()
 	case ArrowKeyUp:
 		cm.selected--
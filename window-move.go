@@ -10,17 +10,15 @@ type MoveWindow struct {
 	done     bool
 	callback func(moved bool)
 
-	pathcache map[*Tile][]Loc
+	paths *PathMap
 
 	*cursorHandler
 }
 
 func (mw *MoveWindow) Render(scr [][]Glyph) {
-	if mw.pathcache == nil {
-		mw.pathcache = make(map[*Tile][]Loc)
-	}
 	loc := mw.Char.Loc()
 	m := mw.World.Map(loc.Map)
+	paths := mw.pathMap()
 	for y := loc.Y - mw.Range; y <= loc.Y+mw.Range; y++ {
 		if y < 0 {
 			continue
@@ -38,21 +36,8 @@ func (mw *MoveWindow) Render(scr [][]Glyph) {
 			if !mw.Self && loc.X == x && loc.Y == y {
 				continue
 			}
-			if abs(loc.X-x)+abs(loc.Y-y) > mw.Range {
-				continue
-			}
-			tile := m.TileAt(x, y)
-			if !tile.Collides && !tile.HasCollider(mw.Char) {
-				var path []Loc
-				if p, ok := mw.pathcache[tile]; ok {
-					path = p
-				} else {
-					path = m.FindPath(loc.X, loc.Y, x, y, mw.Char)
-					mw.pathcache[tile] = path
-				}
-				if path != nil && len(path) <= mw.Range {
-					scr[y][x].BG = ColorNavy
-				}
+			if paths.Reachable(x, y) {
+				scr[y][x].BG = ColorNavy
 			}
 		}
 	}
@@ -93,9 +78,7 @@ func (mw *MoveWindow) Click(coords Coords) bool {
 		return false
 	}
 	loc := mw.Char.Loc()
-	m := mw.World.Map(loc.Map)
-	path := m.FindPath(loc.X, loc.Y, coords.x, coords.y, mw.Char)
-	if len(path) > mw.Range {
+	if abs(loc.X-coords.x)+abs(loc.Y-coords.y) > mw.Range {
 		// TODO: idk if we should *always* cancel
 		if mw.callback != nil {
 			mw.callback(false)
@@ -105,6 +88,7 @@ func (mw *MoveWindow) Click(coords Coords) bool {
 		//mw.Sesh.Bell()
 		return true
 	}
+	path := mw.pathMap().PathTo(coords.x, coords.y)
 	if len(path) == 0 {
 		mw.Sesh.Bell()
 		return true
@@ -117,6 +101,16 @@ func (mw *MoveWindow) Click(coords Coords) bool {
 	return true
 }
 
+// pathMap floods the map from the moving unit once, the first time it's needed.
+// Nothing else moves while this window is open, so it's safe to keep.
+func (mw *MoveWindow) pathMap() *PathMap {
+	if mw.paths == nil {
+		loc := mw.Char.Loc()
+		mw.paths = mw.World.Map(loc.Map).Flood(loc, mw.Range, mw.Char)
+	}
+	return mw.paths
+}
+
 func (mw *MoveWindow) ShouldRemove() bool {
 	return mw.done
 }
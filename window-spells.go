@@ -17,7 +17,7 @@ func (gw *SpellsWindow) Render(scr [][]Glyph) {
 	var lines = []string{"Which spell to cast? (ESC to cancel)", ""}
 	for i, spell := range spells {
 		opt := string(rune('a' + i))
-		lines = append(lines, fmt.Sprintf("%s) %s (%d MP)", opt, spell.Name, spell.MPCost))
+		lines = append(lines, fmt.Sprintf("%s) %s", opt, spellLabel(gw.Char, spell)))
 	}
 	drawCenteredBox(scr, lines, Color256(53))
 }
@@ -35,9 +35,7 @@ func (gw *SpellsWindow) Input(input string) bool {
 			i := int(input[0] - 'a')
 			spells := gw.Char.Spells()
 			if i >= 0 && i < len(spells) {
-				if spells[i].MPCost > gw.Char.MP() {
-					gw.Sesh.Bell()
-					gw.Sesh.Send(GlyphsOf(fmt.Sprintf("Not enough MP to cast %s.", spells[i].Name)))
+				if !canCast(gw.Sesh, gw.Char, spells[i]) {
 					return true
 				}
 				gw.callback(i)
@@ -59,3 +57,31 @@ func (gw *SpellsWindow) ShouldRemove() bool {
 func (gw *SpellsWindow) Mouseover(_ Coords) bool {
 	return false
 }
+
+// spellLabel describes a spell for menus, like "meteor (8 MP, charge, 2 turns)".
+func spellLabel(m *Mob, spell Weapon) string {
+	label := fmt.Sprintf("%s (%d MP", spell.Name, spell.MPCost)
+	if spell.Charge > 0 {
+		label += ", charge"
+	}
+	if cd := m.Cooldown(spell); cd > 0 {
+		label += fmt.Sprintf(", %d turns", cd)
+	}
+	return label + ")"
+}
+
+// canCast checks whether m can cast spell right now, and tells the player why not.
+func canCast(sesh *Sesh, m *Mob, spell Weapon) bool {
+	if m.CanCast(spell) {
+		return true
+	}
+	sesh.Bell()
+	if m.Silenced() {
+		sesh.Send(GlyphsOf(fmt.Sprintf("%s is silenced and can't cast spells.", m.Name())))
+	} else if cd := m.Cooldown(spell); cd > 0 {
+		sesh.Send(GlyphsOf(fmt.Sprintf("%s is on cooldown for %d more turns.", spell.Name, cd)))
+	} else {
+		sesh.Send(GlyphsOf(fmt.Sprintf("Not enough MP to cast %s.", spell.Name)))
+	}
+	return false
+}
@@ -8,11 +8,12 @@ import (
 )
 
 type TeamWindow struct {
-	World *World
-	Sesh  *Sesh
-	Team  Team
-	Win   bool
-	done  bool
+	World    *World
+	Sesh     *Sesh
+	Team     Team
+	Win      bool
+	done     bool
+	callback func() // called when closed
 }
 
 func (gw *TeamWindow) Render(scr [][]Glyph) {
@@ -20,6 +21,13 @@ func (gw *TeamWindow) Render(scr [][]Glyph) {
 		copyString(scr[len(scr)-3], "", true)
 		copyString(scr[len(scr)-2], "Congratulations and thank you for playing!", true)
 		copyString(scr[len(scr)-1], "Press ENTER to return to the title screen.", true)
+	} else if gw.Team.ID == PlayerTeam {
+		auto := "off"
+		if gw.World.autoBattle {
+			auto = "on"
+		}
+		copyString(scr[len(scr)-2], fmt.Sprintf("Press 1-%d to change a unit's AI stance, or A to toggle auto-battle (%s).", len(gw.Team.Units), auto), true)
+		copyString(scr[len(scr)-1], "Team summary: press TAB to switch teams, or ESC to exit.", true)
 	} else {
 		copyString(scr[len(scr)-1], "Team summary: press TAB to switch teams, or ESC to exit.", true)
 	}
@@ -64,6 +72,9 @@ func (gw *TeamWindow) Render(scr [][]Glyph) {
 			fmt.Fprint(w, "\t")
 		}
 		fmt.Fprintf(w, "HP: %d/%d", unit.HP(), unit.MaxHP())
+		if shield := unit.Shield(); shield.HP > 0 {
+			fmt.Fprintf(w, "+%d", shield.HP)
+		}
 	}
 	fmt.Fprintln(w)
 
@@ -126,6 +137,58 @@ func (gw *TeamWindow) Render(scr [][]Glyph) {
 	}
 	fmt.Fprintln(w)
 
+	for i := 0; i < len(gw.Team.Units); i++ {
+		unit := gw.Team.Units[i]
+		if i != 0 {
+			fmt.Fprint(w, "\t")
+		}
+		if unit.Reaction().Type != ReactionNone {
+			fmt.Fprint(w, "↺ ", unit.Reaction())
+		}
+	}
+	fmt.Fprintln(w)
+
+	if gw.Team.ID == PlayerTeam {
+		for i := 0; i < len(gw.Team.Units); i++ {
+			unit := gw.Team.Units[i]
+			if i != 0 {
+				fmt.Fprint(w, "\t")
+			}
+			switch {
+			case unit.stance != StanceManual:
+				fmt.Fprintf(w, "%d) AI: %s", i+1, unit.stance)
+			case gw.World.autoBattle:
+				fmt.Fprintf(w, "%d) AI: auto", i+1)
+			default:
+				fmt.Fprintf(w, "%d) AI: off", i+1)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	for _, aff := range []Affinity{AffinityWeak, AffinityResist, AffinityImmune, AffinityAbsorb} {
+		any := false
+		for _, unit := range gw.Team.Units {
+			if len(unit.Affinities().With(aff)) > 0 {
+				any = true
+				break
+			}
+		}
+		if !any {
+			continue
+		}
+		for i := 0; i < len(gw.Team.Units); i++ {
+			unit := gw.Team.Units[i]
+			if i != 0 {
+				fmt.Fprint(w, "\t")
+			}
+			if names := unit.Affinities().Names(aff); names != "" {
+				fmt.Fprintf(w, "%s: %s", strings.Title(aff.String()), names)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
 	for spell := 0; spell < spellCount; spell++ {
 		for i := 0; i < len(gw.Team.Units); i++ {
 			unit := gw.Team.Units[i]
@@ -168,24 +231,41 @@ func (gw *TeamWindow) Input(input string) bool {
 	if len(input) == 1 {
 		switch input[0] {
 		case EscKey, EnterKey:
-			gw.done = true
+			gw.close()
 		case TabKey: // tab
 			if gw.Team.ID == PlayerTeam {
 				gw.Team = gw.World.battle.Teams[AITeam]
 			} else {
 				gw.Team = gw.World.battle.Teams[PlayerTeam]
 			}
+		case 'a', 'A':
+			if gw.Team.ID == PlayerTeam {
+				gw.World.autoBattle = !gw.World.autoBattle
+			}
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			i := int(input[0] - '1')
+			if gw.Team.ID == PlayerTeam && i < len(gw.Team.Units) {
+				unit := gw.Team.Units[i]
+				unit.stance = unit.stance.Next()
+			}
 		}
 	}
 
 	return true
 }
 
+func (gw *TeamWindow) close() {
+	gw.done = true
+	if gw.callback != nil {
+		gw.callback()
+	}
+}
+
 func (gw *TeamWindow) Click(_ Coords) bool {
 	if gw.Team.ID == PlayerTeam {
 		gw.Team = gw.World.battle.Teams[AITeam]
 	} else {
-		gw.done = true
+		gw.close()
 	}
 	return true
 }
@@ -30,6 +30,21 @@ func (mw *TitleWindow) Render(scr [][]Glyph) {
 	copyString(scr[14], " * Click on this screen to focus it.", true)
 	copyString(scr[15], " * Then press ENTER to start a new game!", true)
 	copyString(scr[16], " ↓ Read the guide on this page below to learn how to play.", true)
+	fog := "off"
+	if mw.World.fog {
+		fog = "on"
+	}
+	copyString(scr[12], " * Press F to toggle fog of war: "+fog, true)
+	difficulty := "normal"
+	if mw.World.thinkTime > 0 {
+		difficulty = "hard (enemies think for " + mw.World.thinkTime.String() + " per turn)"
+	}
+	copyString(scr[13], " * Press H to change difficulty: "+difficulty, true)
+	accessible := "off"
+	if mw.Sesh.accessible {
+		accessible = "on (no mouse or blinking; l lists units)"
+	}
+	copyString(scr[11], " * Press A for accessible mode: "+accessible, true)
 
 	copyString(scr[18], " (Note to 7DRL judges: see description for original 7DRL version)", true)
 
@@ -50,6 +65,17 @@ func (mw *TitleWindow) Input(input string) bool {
 	case EnterKey:
 		mw.World.StartBattle(0)
 		mw.done = true
+	case 'a', 'A':
+		mw.Sesh.SetAccessible(!mw.Sesh.accessible)
+	case 'f', 'F':
+		mw.World.fog = !mw.World.fog
+	case 'h', 'H':
+		for i, t := range thinkTimes {
+			if t == mw.World.thinkTime {
+				mw.World.thinkTime = thinkTimes[(i+1)%len(thinkTimes)]
+				break
+			}
+		}
 	}
 	return true
 }
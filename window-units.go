@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+)
+
+// UnitListWindow is a plain text page listing units, for accessible mode.
+// See nearbyUnits.
+type UnitListWindow struct {
+	World *World
+	Sesh  *Sesh
+	Lines []string
+
+	scroll int
+	done   bool
+}
+
+func (uw *UnitListWindow) Render(scr [][]Glyph) {
+	for y := range scr {
+		copyString(scr[y], "", true)
+	}
+	copyString(scr[0], "Units you can see, nearest first:", true)
+	rows := uw.rows(len(scr[0]) - 1)
+	for i, row := range rows[min(uw.scroll, len(rows)):] {
+		if 2+i >= len(scr)-1 {
+			break
+		}
+		copyString(scr[2+i], row, true)
+	}
+	if len(rows) == 0 {
+		copyString(scr[2], "Nobody.", true)
+	}
+	copyString(scr[len(scr)-1], "Arrow keys to scroll, ESC or enter to close.", true)
+}
+
+// rows word wraps the lines to fit width, indenting the continued parts.
+func (uw *UnitListWindow) rows(width int) []string {
+	var rows []string
+	for _, line := range uw.Lines {
+		row := ""
+		for _, word := range strings.Fields(line) {
+			if row != "" && len(row)+1+len(word) > width {
+				rows = append(rows, row)
+				row = "  "
+			}
+			if row != "" && row != "  " {
+				row += " "
+			}
+			row += word
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (uw *UnitListWindow) Cursor() Coords {
+	return Coords{x: 0, y: 2}
+}
+
+func (uw *UnitListWindow) Input(input string) bool {
+	switch input {
+	case ArrowKeyUp:
+		uw.scroll = max(uw.scroll-1, 0)
+		return true
+	case ArrowKeyDown:
+		uw.scroll = min(uw.scroll+1, max(len(uw.rows(uw.Sesh.disp.w-1))-1, 0))
+		return true
+	}
+	switch input[0] {
+	case EscKey, EnterKey, 'l', 'q':
+		uw.done = true
+	}
+	return true
+}
+
+func (uw *UnitListWindow) Click(_ Coords) bool {
+	return true
+}
+
+func (uw *UnitListWindow) Mouseover(_ Coords) bool {
+	return false
+}
+
+func (uw *UnitListWindow) ShouldRemove() bool {
+	return uw.done
+}
+
+func (uw *UnitListWindow) close() {
+	uw.done = true
+}
+
+var (
+	_ Window = (*UnitListWindow)(nil)
+)
@@ -122,6 +122,32 @@ func drawCenteredBox(scr [][]Glyph, lines []string, bgColor Color) {
 	}
 }
 
+// drawPanel draws lines in a box of the given width at column x, from the top of the screen.
+// It stops before the combat log.
+func drawPanel(scr [][]Glyph, lines [][]Glyph, x, width int, bgColor Color) {
+	const reserved = 7 // combat log and bottom UI
+	for i := 0; i < len(lines)+2; i++ {
+		y := 1 + i
+		if y >= len(scr)-reserved {
+			break
+		}
+		row := scr[y][x:min(x+width, len(scr[y]))]
+		for j := range row {
+			row[j] = GlyphOf(' ')
+		}
+		if i > 0 && i <= len(lines) && len(row) > 2 {
+			line := lines[i-1]
+			if len(line) > len(row)-2 {
+				line = line[:len(row)-2]
+			}
+			copyGlyphsOffset(row, line, 1)
+		}
+		for j := range row {
+			row[j].BG = bgColor
+		}
+	}
+}
+
 func copyString(dst []Glyph, src string, padRight bool) {
 	x := 0
 	for _, r := range src {
@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -18,6 +19,7 @@ type World struct {
 	maps     map[string]*Map
 	objects  map[ID]Object
 	seshes   map[*Sesh]struct{}
+	bots     map[*Bot]struct{}
 	waitlist []Turner
 
 	// battle state
@@ -29,18 +31,25 @@ type World struct {
 	busy   *int32
 
 	// overall game state
-	player    Team
-	current   *Map
-	gameOver  bool
-	battleWon bool
-	level     int
-	battle    Battle
-	score     int
+	player     Team
+	current    *Map
+	gameOver   bool
+	battleWon  bool
+	level      int
+	battle     Battle
+	score      int
+	fog        bool          // fog of war mode
+	autoBattle bool          // the AI plays for the player's team
+	thinkTime  time.Duration // hard mode: how long the enemy AI searches each turn, 0 = normal
+
+	// what each team can see in fog of war mode, see Vision
+	visions map[int]*Vision
 
 	apply      chan Action
 	applySync  chan Action // this exists so the shutdown hook is guaranteed to run
 	push       chan StateAction
 	pushBottom chan StateAction
+	wakeup     chan struct{} // see Wake
 }
 
 type Action interface {
@@ -51,11 +60,18 @@ type StateAction interface {
 	Run(*World) bool
 }
 
+// Idler is a StateAction that blocks the state stack without needing to run every tick.
+// Idlers waiting on something outside the world goroutine should call World.Wake when it's ready.
+type Idler interface {
+	Idle() bool
+}
+
 func newWorld() *World {
 	w := &World{
 		maps:    make(map[string]*Map),
 		objects: make(map[ID]Object),
 		seshes:  make(map[*Sesh]struct{}),
+		bots:    make(map[*Bot]struct{}),
 
 		busy: new(int32),
 
@@ -65,6 +81,7 @@ func newWorld() *World {
 		applySync:  make(chan Action),
 		push:       make(chan StateAction, 32),
 		pushBottom: make(chan StateAction, 32),
+		wakeup:     make(chan struct{}, 1),
 	}
 	mapnames := map[string]struct{}{}
 	for _, maps := range mapsByLevel {
@@ -92,6 +109,7 @@ func (w *World) reset() {
 	w.up = nil
 	w.gameOver = false
 	w.score = 0
+	w.autoBattle = false
 	w.objects = make(map[ID]Object)
 	w.player = generatePlayerTeam()
 	w.current = nil
@@ -165,43 +183,106 @@ func (w *World) Tick() {
 }
 
 func (w *World) Run() {
-	ticker := time.NewTicker(tickTime)
-	defer ticker.Stop()
+	// wake is nil while the world is idle, so the loop only
+	// wakes up for input or the next scheduled animation frame.
+	var wake <-chan time.Time
+	var wakeAt time.Time
+	var wakeTick int64
 	for {
+		if n := w.sleepTicks(); n > 0 {
+			at := time.Now().Add(tickTime * time.Duration(n))
+			if wake == nil || at.Before(wakeAt) {
+				wake = time.After(time.Until(at))
+				wakeAt = at
+				wakeTick = w.tick + n
+			}
+		}
+
 		select {
 		case a := <-w.apply:
 			a.Apply(w)
+			w.answerBots()
 			w.notify()
 		case a := <-w.applySync:
 			a.Apply(w)
+			w.answerBots()
 			w.notify()
 		case a := <-w.push:
 			w.state = append(w.state, a)
 		case a := <-w.pushBottom:
 			w.state = append([]StateAction{a}, w.state...)
-		case <-ticker.C:
-			if len(w.state) > 0 {
-				state := w.state[len(w.state)-1]
-				if state.Run(w) {
-					w.state = w.state[:len(w.state)-1]
-				}
-				busy := int32(0)
-				if len(w.state) > 0 {
-					busy = 1
-				}
-				atomic.StoreInt32(w.busy, busy)
-			}
-			w.Tick()
-			if !w.gameOver {
-				if w.shouldEndGame() {
-					w.endGame()
-				} else if !w.battleWon && w.shouldWin() {
-					w.winBattle()
-				}
+		case <-w.wakeup:
+			// go around again to see if the top state has work to do
+		case <-wake:
+			wake = nil
+			// skip over the ticks we slept through
+			if wakeTick > w.tick+1 {
+				w.tick = wakeTick - 1
 			}
-			w.notify()
+			w.step()
+		}
+	}
+}
+
+// step runs one tick of the world: the top state, tickers, and win/loss checks.
+func (w *World) step() {
+	if len(w.state) > 0 {
+		state := w.state[len(w.state)-1]
+		if state.Run(w) {
+			w.state = w.state[:len(w.state)-1]
+		}
+		busy := int32(0)
+		if len(w.state) > 0 {
+			busy = 1
+		}
+		atomic.StoreInt32(w.busy, busy)
+	}
+	w.Tick()
+	if !w.gameOver {
+		if w.shouldEndGame() {
+			w.endGame()
+		} else if !w.battleWon && w.shouldWin() {
+			w.winBattle()
 		}
 	}
+	w.answerBots()
+	w.notify()
+}
+
+// sleepTicks returns how many ticks the world can sleep before something needs to run.
+// Zero means nothing is scheduled and the world can sleep until the next input.
+func (w *World) sleepTicks() int64 {
+	if len(w.state) > 0 {
+		if idle, ok := w.state[len(w.state)-1].(Idler); !ok || !idle.Idle() {
+			return 1
+		}
+	}
+	var next int64 = -1
+	for _, obj := range w.objects {
+		ticker, ok := obj.(Ticker)
+		if !ok {
+			continue
+		}
+		at := ticker.NextTick(w, w.tick)
+		if at < 0 {
+			continue
+		}
+		if next < 0 || at < next {
+			next = at
+		}
+	}
+	if next < 0 {
+		return 0
+	}
+	return max64(1, next-w.tick)
+}
+
+// Wake makes the world loop check on an idle state again. It's safe to call from any goroutine.
+func (w *World) Wake() {
+	select {
+	case w.wakeup <- struct{}{}:
+	default:
+	}
 }
 
 func (w *World) Busy() bool {
@@ -217,7 +298,7 @@ func (w *World) NextTurn() {
 	allDead := true
 	for _, t := range w.waitlist {
 		if m, ok := t.(*Mob); ok {
-			if m.CanAct() || m.CanMove() {
+			if !m.Dead() {
 				allDead = false
 				break
 			}
@@ -272,12 +353,18 @@ func (w *World) Broadcast(args ...interface{}) {
 	for sesh := range w.seshes {
 		sesh.Send(msg)
 	}
+	for bot := range w.bots {
+		bot.addLog(strings.TrimPrefix(PlainText(msg), "· "))
+	}
 }
 
 func (w *World) BroadcastString(msg string) {
 	for sesh := range w.seshes {
 		sesh.Send(GlyphsOf("· " + msg))
 	}
+	for bot := range w.bots {
+		bot.addLog(msg)
+	}
 }
 
 func (w *World) notify() {
@@ -290,11 +377,10 @@ func (w *World) shouldEndGame() bool {
 	if w.current == nil {
 		return false
 	}
-	for _, obj := range w.current.Objects {
-		if mob, ok := obj.(*Mob); ok && mob.Team() == PlayerTeam {
-			if !mob.Dead() {
-				return false
-			}
+	// go by the teams units started on, so charmed units don't count
+	for _, mob := range w.battle.Teams[PlayerTeam].Units {
+		if !mob.Dead() {
+			return false
 		}
 	}
 	return true
@@ -304,8 +390,11 @@ func (w *World) shouldWin() bool {
 	if w.current == nil {
 		return false
 	}
-	for _, obj := range w.current.Objects {
-		if mob, ok := obj.(*Mob); ok && mob.Team() != PlayerTeam {
+	for _, team := range w.battle.Teams {
+		if team.ID == PlayerTeam {
+			continue
+		}
+		for _, mob := range team.Units {
 			if !mob.Dead() {
 				return false
 			}
@@ -369,12 +458,14 @@ func (w *World) StartBattle(level int) {
 
 	m := w.Map(battle.Map)
 	m.Reset()
+	w.placeTraps(m)
 	w.level = level
 	w.current = m
 	w.turn = 0
 	w.waitlist = nil
 	w.battleWon = false
 	w.battle = battle
+	w.visions = nil
 
 	n := 0
 	for teamID, team := range battle.Teams {
@@ -390,6 +481,7 @@ func (w *World) StartBattle(level int) {
 		gw := &GameWindow{World: w, Map: m, Team: PlayerTeam, Sesh: sesh}
 		sesh.PushWindow(gw)
 		sesh.win = gw
+		sesh.lastTurn = -1
 	}
 
 	w.NextTurn()
@@ -404,9 +496,91 @@ func (w *World) Add(obj Object) {
 }
 
 func (w *World) Attack(target *Mob, source *Mob, weapon Weapon) {
+	w.attack(target, source, weapon, true)
+}
+
+// attack resolves an attack. react is false for attacks made by reactions,
+// so that units can't keep countering each other forever.
+func (w *World) attack(target *Mob, source *Mob, weapon Weapon, react bool) {
+	if react && target.reacts(ReactionFirstStrike, source, weapon) {
+		w.strikeBack(target, source)
+		if !source.CanAct() {
+			return
+		}
+	}
+
+	flank := attackFlank(source, source.Loc(), target, weapon)
+	hit, crit := rollHit(w, source, target, weapon)
+	if target != source {
+		source.FaceToward(target.Loc())
+	}
+	if hit && react && target.reacts(ReactionDodge, source, weapon) {
+		w.showReaction(target)
+		w.Broadcast(
+			source.NameColored(),
+			" attacked ",
+			target.NameColored(),
+			" with ",
+			weapon.Name,
+			", but they ",
+			GlyphsOf("dodged", StyleBold),
+			" it!",
+		)
+		return
+	}
+	if !hit {
+		w.Broadcast(
+			source.NameColored(),
+			" attacked ",
+			target.NameColored(),
+			" with ",
+			weapon.Name,
+			", but ",
+			GlyphsOf("missed", StyleFG(Color256(245))),
+			".",
+		)
+		return
+	}
+	var critText []Glyph
+	if crit {
+		critText = GlyphsOf("Critical! ", StyleFG(ColorBrightYellow), StyleBold)
+	}
+	dmg := 0
 	if weapon.Damage.Type != DamageNone {
-		dmg := target.Damage(w, weapon.Damage)
-		if dmg < 0 {
+		damage := weapon.Damage
+		damage.Scale = flank.DamageScale()
+		if crit {
+			damage.Scale = damage.Scale * weapon.CritMultiplier() / 100
+		}
+		shielded := target.Shield().HP
+		dmg = target.Damage(w, damage)
+		blocked := shielded - target.Shield().HP
+		aff := AffinityNormal
+		if damage.Type != DamageHealing {
+			aff = target.Affinities().Of(damage.Element)
+		}
+		switch {
+		case aff == AffinityImmune:
+			w.Broadcast(
+				source.NameColored(),
+				" attacked ",
+				target.NameColored(),
+				" with ",
+				weapon.Name,
+				", but it's immune to ",
+				GlyphsOf(damage.Element.String(), StyleFG(damage.Element.Color())),
+				".",
+			)
+		case aff == AffinityAbsorb:
+			w.Broadcast(
+				target.NameColored(),
+				" absorbed ",
+				weapon.Name,
+				" and recovered ",
+				ColorDamage(dmg),
+				" HP!",
+			)
+		case dmg < 0:
 			w.Broadcast(
 				source.NameColored(),
 				" healed ",
@@ -417,8 +591,9 @@ func (w *World) Attack(target *Mob, source *Mob, weapon Weapon) {
 				ColorDamage(dmg),
 				" HP!",
 			)
-		} else {
+		default:
 			w.Broadcast(
+				critText,
 				source.NameColored(),
 				" attacked ",
 				target.NameColored(),
@@ -426,7 +601,11 @@ func (w *World) Attack(target *Mob, source *Mob, weapon Weapon) {
 				weapon.Name,
 				" for ",
 				ColorDamage(dmg),
-				" damage!",
+				" damage",
+				blockedText(blocked),
+				flankText(flank),
+				affinityText(aff),
+				"!",
 			)
 		}
 	}
@@ -438,9 +617,33 @@ func (w *World) Attack(target *Mob, source *Mob, weapon Weapon) {
 			target.NameColored(),
 			" died.",
 		)
+		return
+	}
+	if react {
+		w.recoverOnHit(target, source, weapon, dmg)
+		if target.reacts(ReactionCounter, source, weapon) {
+			w.strikeBack(target, source)
+		}
 	}
 }
 
+func affinityText(aff Affinity) string {
+	switch aff {
+	case AffinityWeak:
+		return ". It's super effective"
+	case AffinityResist:
+		return ". It's not very effective"
+	}
+	return ""
+}
+
+func flankText(flank Flank) string {
+	if flank == FlankFront {
+		return ""
+	}
+	return " " + flank.String()
+}
+
 type EnqueueAction struct {
 	ID     ID
 	Action func(*Mob, *World)
@@ -555,8 +758,15 @@ func (ms *MoveState) Run(w *World) bool {
 	ms.wait = 0
 	loc := ms.Path[ms.i]
 	m := w.Map(loc.Map)
+	if mob, ok := ms.Obj.(*Mob); ok {
+		mob.FaceToward(loc)
+	}
 	m.Move(ms.Obj, loc.X, loc.Y)
 	ms.i++
+	if mob, ok := ms.Obj.(*Mob); ok && w.collide(mob) {
+		// stepped on something nasty
+		ms.i = len(ms.Path)
+	}
 	if ms.i != len(ms.Path) {
 		return false
 	}
@@ -585,6 +795,14 @@ func (as *AttackState) Run(w *World) bool {
 		for _, t := range as.Targets {
 			w.Attack(t, as.Char, wep)
 		}
+		for _, loc := range as.HitLocs {
+			w.DamageTile(loc, as.Char, wep)
+		}
+		if wep.Place != nil {
+			for _, loc := range as.HitLocs {
+				wep.Place(w, as.Char, loc)
+			}
+		}
 		return true
 	}
 	var onend func(*World)
@@ -620,116 +838,17 @@ func (as *AttackState) Run(w *World) bool {
 	if wep.MPCost > 0 {
 		as.Char.AddMP(-wep.MPCost)
 	}
+	as.Char.StartCooldown(wep)
 	as.done = true
 	return false
 }
 
-type EnemyAIState struct {
-	self   *Mob
-	target *Mob
-	moved  bool
-	acted  bool
-
-	// path  []Loc
-	// pause int
-}
-
-func (ai *EnemyAIState) Run(w *World) bool {
-	loc := ai.self.Loc()
-	m := w.Map(loc.Map)
-
-	if ai.self.Dead() {
-		w.NextTurn()
-		return true
-	}
-
-	if ai.target == nil {
-		var path []Loc
-		for _, obj := range m.Objects {
-			mob, ok := obj.(*Mob)
-			if !ok {
-				continue
-			}
-			if mob.Team() == ai.self.Team() {
-				continue
-			}
-			if mob.Dead() {
-				continue
-			}
-			if ai.self.tauntedBy != nil && !ai.self.tauntedBy.Dead() && mob.ID() != ai.self.tauntedBy.ID() {
-				continue
-			}
-
-			if ai.self.CanAttack(w, mob, ai.self.Weapon()) {
-				ai.target = mob
-				// TODO: maybe run away when too close
-				return false
-			}
-
-			// otherloc := mob.Loc()
-			// newpath := m.FindPath(loc.X, loc.Y, otherloc.X, otherloc.Y, ai.self, mob)
-			newpath := m.FindPathNextTo(ai.self, mob)
-			if len(newpath) == 0 {
-				continue
-			}
-			for i := 0; i < len(newpath)-1; i++ {
-				if ai.self.CanAttackFrom(w, newpath[i], mob, ai.self.Weapon()) {
-					newpath = newpath[:i+1]
-					fmt.Println("AI shorter path:", newpath)
-					break
-				}
-			}
-			if path == nil || len(newpath) < len(path) {
-				path = newpath
-				ai.target = mob
-			}
-		}
-		if path != nil {
-			if len(path) == 0 {
-				// already close
-				return false
-			}
-			if len(path) > ai.self.MoveRange() {
-				path = path[:ai.self.MoveRange()]
-			}
-			ai.moved = true
-			w.push <- &MoveState{Obj: ai.self, Path: path}
-			return false
-		}
-		ai.self.FinishTurn(w, ai.moved, ai.acted)
-		w.NextTurn()
-		return true
-	}
-
-	if ai.self.CanAttack(w, ai.target, ai.self.Weapon()) {
-		wep := ai.self.Weapon()
-		var hitlocs, projpath []Loc
-		var targets []*Mob
-		if wep.Magic {
-			targets, hitlocs = findTargets(ai.target.Loc(), m, true, wep.HitboxSize, wep.Hitbox)
-			_, _, projpath = m.Raycast(loc, ai.target.Loc(), true)
-		} else {
-			t, _, path := m.Raycast(ai.self.Loc(), ai.target.Loc(), false)
-			targets = []*Mob{t}
-			projpath = path
-		}
-		w.push <- &AttackState{
-			Char:     ai.self,
-			Weapon:   wep,
-			HitLocs:  hitlocs,
-			ProjPath: projpath,
-			Targets:  targets,
-		}
-		ai.acted = true
-	}
-
-	ai.self.FinishTurn(w, ai.moved, ai.acted)
-	w.pushBottom <- NextTurnState{}
-	return true
-}
-
 type GameOverState struct{}
 
 func (gos GameOverState) Run(w *World) bool {
 	return false
 }
+
+func (GameOverState) Idle() bool {
+	return true
+}